@@ -0,0 +1,443 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	durationType = reflect.TypeOf(time.Duration(0))
+	timeType     = reflect.TypeOf(time.Time{})
+	urlType      = reflect.TypeOf(&url.URL{})
+	ipType       = reflect.TypeOf(net.IP{})
+	ipNetType    = reflect.TypeOf(&net.IPNet{})
+	mapType      = reflect.TypeOf(map[string]string{})
+)
+
+// Unmarshal 将section中的键按照`ini`标签填充到结构体中，结构体类型的字段对应"section.字段名"的子段落，
+// section为空时只处理结构体类型的字段，此时结构体类型的字段对应同名的段落。键不存在时使用`default`标签中的值，都不存在则保持原值
+func (f *FileReader) Unmarshal(section string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("unmarshal target must be a non-nil pointer to struct")
+	}
+	return f.unmarshal(section, rv.Elem())
+}
+
+// Marshal 将结构体中的字段写入section，结构体类型的字段写入对应的子段落
+func (f *FileReader) Marshal(section string, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return errors.New("marshal source is nil")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return errors.New("marshal source must be a struct")
+	}
+	return f.marshal(section, rv)
+}
+
+func fieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	name := field.Tag.Get("ini")
+	if name == "-" {
+		return "", false
+	}
+	if idx := strings.Index(name, ","); idx >= 0 {
+		name = name[:idx]
+	}
+	if name == "" {
+		name = field.Name
+	}
+	return name, true
+}
+
+// hasOption `ini`标签中逗号后面的选项，例如`ini:"buffer,size"`表示按GetAsSize解析
+func hasOption(field reflect.StructField, opt string) bool {
+	opts := strings.Split(field.Tag.Get("ini"), ",")
+	for _, o := range opts[1:] {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpecial(t reflect.Type) bool {
+	switch t {
+	case durationType, timeType, urlType, ipType, ipNetType:
+		return true
+	}
+	return false
+}
+
+func isSection(t reflect.Type) bool {
+	if isSpecial(t) {
+		return false
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+func (f *FileReader) unmarshal(section string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if isSection(field.Type) {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					fv.Set(reflect.New(field.Type.Elem()))
+				}
+				fv = fv.Elem()
+			}
+			if err := f.unmarshal(childSection(section, name), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isTables(field.Type) {
+			if err := f.unmarshalTables(childSection(section, name), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if section == "" && f.root == nil {
+			continue
+		}
+		if field.Type == mapType {
+			if m := f.GetMap(section + "::" + name); len(m) > 0 {
+				fv.Set(reflect.ValueOf(m))
+			}
+			continue
+		}
+
+		c, ok, err := f.lookup(section, name)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			def, ok := field.Tag.Lookup("default")
+			if !ok {
+				continue
+			}
+			c = &conv{data: def}
+		}
+		if hasOption(field, "size") {
+			err = c.assignSize(fv)
+		} else {
+			err = c.assign(fv)
+		}
+		if err != nil {
+			return fmt.Errorf("%s::%s: %v", section, name, err)
+		}
+	}
+	return nil
+}
+
+func (f *FileReader) marshal(section string, rv reflect.Value) error {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if isSection(field.Type) {
+			if fv.Kind() == reflect.Ptr {
+				if fv.IsNil() {
+					continue
+				}
+				fv = fv.Elem()
+			}
+			if err := f.marshal(childSection(section, name), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if isTables(field.Type) {
+			if err := f.marshalTables(childSection(section, name), fv); err != nil {
+				return err
+			}
+			continue
+		}
+		if section == "" && f.root == nil {
+			continue
+		}
+		if field.Type == mapType {
+			m := fv.Interface().(map[string]string)
+			keys := make([]string, 0, len(m))
+			for k := range m {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				if err := f.Set(section+"::"+name+"."+k, m[k]); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		str, err := format(fv)
+		if err != nil {
+			return fmt.Errorf("%s::%s: %v", section, name, err)
+		}
+		if err := f.Set(section+"::"+name, str); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isTables 结构体切片对应重复出现的段落
+func isTables(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && isSection(t.Elem())
+}
+
+func (f *FileReader) unmarshalTables(name string, fv reflect.Value) error {
+	tables := f.Tables(name)
+	if len(tables) == 0 {
+		return nil
+	}
+	declared := f.tableDeclared(name)
+	out := reflect.MakeSlice(fv.Type(), len(tables), len(tables))
+	for i := range tables {
+		elem := out.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			elem.Set(reflect.New(elem.Type().Elem()))
+			elem = elem.Elem()
+		}
+		space := name
+		if declared {
+			space = tableName(name, i)
+		}
+		if err := f.unmarshal(space, elem); err != nil {
+			return err
+		}
+	}
+	fv.Set(out)
+	return nil
+}
+
+func (f *FileReader) marshalTables(name string, fv reflect.Value) error {
+	if fv.Len() == 1 && !f.tableDeclared(name) {
+		return f.marshal(name, reflect.Indirect(fv.Index(0)))
+	}
+	count := f.tableCount(name)
+	for i := 0; i < fv.Len(); i++ {
+		elem := fv.Index(i)
+		if elem.Kind() == reflect.Ptr {
+			if elem.IsNil() {
+				continue
+			}
+			elem = elem.Elem()
+		}
+		if i >= count {
+			if _, err := f.AppendTable(name); err != nil {
+				return err
+			}
+		}
+		if err := f.marshal(tableName(name, i), elem); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assign 使用conv中的转换规则为字段赋值
+func (c *conv) assign(fv reflect.Value) error {
+	if isSpecial(fv.Type()) {
+		return c.assignSpecial(fv)
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(c.GetAsString())
+	case reflect.Bool:
+		b, err := c.GetAsBool()
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := c.GetAsInt64()
+		if err != nil {
+			return err
+		}
+		if fv.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, fv.Type())
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := c.GetAsUint64()
+		if err != nil {
+			return err
+		}
+		if fv.OverflowUint(n) {
+			return fmt.Errorf("%d overflows %s", n, fv.Type())
+		}
+		fv.SetUint(n)
+	case reflect.Float32:
+		n, err := c.GetAsFloat32()
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(float64(n))
+	case reflect.Float64:
+		n, err := c.GetAsFloat64()
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(n)
+	case reflect.Slice:
+		return c.assignSlice(fv)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func (c *conv) assignSize(fv reflect.Value) error {
+	n, err := c.GetAsSize()
+	if err != nil {
+		return err
+	}
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if fv.OverflowInt(n) {
+			return fmt.Errorf("%d overflows %s", n, fv.Type())
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if n < 0 || fv.OverflowUint(uint64(n)) {
+			return fmt.Errorf("%d overflows %s", n, fv.Type())
+		}
+		fv.SetUint(uint64(n))
+	default:
+		return fmt.Errorf("size option requires an integer field, got %s", fv.Type())
+	}
+	return nil
+}
+
+// assignSpecial 处理time.Duration、time.Time、*url.URL、net.IP和*net.IPNet
+func (c *conv) assignSpecial(fv reflect.Value) error {
+	var (
+		v   interface{}
+		err error
+	)
+	switch fv.Type() {
+	case durationType:
+		v, err = c.GetAsDuration()
+	case timeType:
+		v, err = c.GetAsTime()
+	case urlType:
+		v, err = c.GetAsURL()
+	case ipType:
+		v, err = c.GetAsIP()
+	case ipNetType:
+		v, err = c.GetAsCIDR()
+	}
+	if err != nil {
+		return err
+	}
+	fv.Set(reflect.ValueOf(v))
+	return nil
+}
+
+func (c *conv) assignSlice(fv reflect.Value) error {
+	var src reflect.Value
+	switch fv.Type().Elem().Kind() {
+	case reflect.String:
+		arr, err := c.GetAsStringSlice()
+		if err != nil {
+			return err
+		}
+		src = reflect.ValueOf(arr)
+	case reflect.Int:
+		arr, err := c.GetAsIntSlice()
+		if err != nil {
+			return err
+		}
+		src = reflect.ValueOf(arr)
+	case reflect.Float64, reflect.Float32:
+		arr, err := c.GetAsFloat64Slice()
+		if err != nil {
+			return err
+		}
+		src = reflect.ValueOf(arr)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	out := reflect.MakeSlice(fv.Type(), src.Len(), src.Len())
+	for i := 0; i < src.Len(); i++ {
+		out.Index(i).Set(src.Index(i).Convert(fv.Type().Elem()))
+	}
+	fv.Set(out)
+	return nil
+}
+
+func format(fv reflect.Value) (string, error) {
+	switch v := fv.Interface().(type) {
+	case time.Duration:
+		return v.String(), nil
+	case time.Time:
+		return v.Format(time.RFC3339), nil
+	case *url.URL:
+		if v == nil {
+			return "", nil
+		}
+		return v.String(), nil
+	case net.IP:
+		if v == nil {
+			return "", nil
+		}
+		return v.String(), nil
+	case *net.IPNet:
+		if v == nil {
+			return "", nil
+		}
+		return v.String(), nil
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, 32), nil
+	case reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'g', -1, 64), nil
+	case reflect.Slice:
+		arr := make([]string, fv.Len())
+		for i := range arr {
+			s, err := format(fv.Index(i))
+			if err != nil {
+				return "", err
+			}
+			arr[i] = s
+		}
+		return "[" + strings.Join(arr, ",") + "]", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", fv.Type())
+}
@@ -0,0 +1,122 @@
+package conf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const bindIni = `[user]
+username = admin
+port = 80
+needInit = true
+arrInt = [1,2,3]
+arrFloat = [1.2,2.1]
+arrString = [h,cz,a]
+
+[administrator]
+username = administrator
+`
+
+type bindUser struct {
+	Username  string    `ini:"username"`
+	Port      uint16    `ini:"port"`
+	NeedInit  bool      `ini:"needInit"`
+	Timeout   int       `ini:"timeout" default:"30"`
+	ArrInt    []int     `ini:"arrInt"`
+	ArrFloat  []float32 `ini:"arrFloat"`
+	ArrString []string  `ini:"arrString"`
+	Ignore    string    `ini:"-"`
+}
+
+type bindConfig struct {
+	User  bindUser `ini:"user"`
+	Admin *struct {
+		Username string `ini:"username"`
+	} `ini:"administrator"`
+}
+
+func TestFileReader_Unmarshal(t *testing.T) {
+	f, name := openTemp(t, bindIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	var cfg bindConfig
+	if err := f.Unmarshal("", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	expect := bindUser{
+		Username:  "admin",
+		Port:      80,
+		NeedInit:  true,
+		Timeout:   30,
+		ArrInt:    []int{1, 2, 3},
+		ArrFloat:  []float32{1.2, 2.1},
+		ArrString: []string{"h", "cz", "a"},
+	}
+	if !reflect.DeepEqual(cfg.User, expect) {
+		t.Errorf("got %+v", cfg.User)
+	}
+	if cfg.Admin == nil || cfg.Admin.Username != "administrator" {
+		t.Errorf("got %+v", cfg.Admin)
+	}
+
+	var user bindUser
+	if err := f.Unmarshal("user", &user); err != nil {
+		t.Fatal(err)
+	}
+	if user.Username != "admin" {
+		t.Errorf("got %+v", user)
+	}
+	if err := f.Unmarshal("user", user); err == nil {
+		t.Error("unmarshal into non pointer should fail")
+	}
+
+	var wrong struct {
+		Username int `ini:"username"`
+	}
+	if err := f.Unmarshal("user", &wrong); err == nil {
+		t.Error("unmarshal string into int should fail")
+	}
+}
+
+func TestFileReader_Marshal(t *testing.T) {
+	f := &FileReader{}
+	cfg := bindConfig{User: bindUser{Username: "root", Port: 22, ArrInt: []int{1, 2}}}
+	if err := f.Marshal("", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	f.WriteTo(&buf)
+	expect := `[user]
+username = root
+port = 22
+needInit = false
+timeout = 0
+arrInt = [1,2]
+arrFloat = []
+arrString = []
+`
+	if buf.String() != expect {
+		t.Errorf("unexpected content:\n%s", buf.String())
+	}
+
+	var back bindConfig
+	if err := f.Unmarshal("", &back); err != nil {
+		t.Fatal(err)
+	}
+	if back.User.Username != "root" || back.User.Port != 22 || !reflect.DeepEqual(back.User.ArrInt, []int{1, 2}) {
+		t.Errorf("got %+v", back.User)
+	}
+}
+
+func TestFileReader_UnmarshalSize(t *testing.T) {
+	f, _ := ParseString("[spider]\nbuffer = 10MB\n", INI)
+	var cfg struct {
+		Buffer int64 `ini:"buffer,size"`
+	}
+	if err := f.Unmarshal("spider", &cfg); err != nil || cfg.Buffer != 10<<20 {
+		t.Errorf("got %d %v", cfg.Buffer, err)
+	}
+}
@@ -0,0 +1,63 @@
+// conf-gen 根据ini文件生成带有`ini`标签的结构体以及加载函数
+//
+//	conf-gen [-o config_gen.go] [-package config] [-type Config] [-import conf] spider.ini
+//
+// -o指定的文件已经存在时沿用其中的类型名和字段名，重新生成不会改变已有字段的名称
+package main
+
+import (
+	"conf"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+func main() {
+	out := flag.String("o", "", "输出文件，默认输出到标准输出")
+	pkg := flag.String("package", "config", "生成代码的包名")
+	typeName := flag.String("type", "Config", "顶层结构体的名称")
+	confImport := flag.String("import", "conf", "conf包的导入路径")
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: conf-gen [flags] file")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0), *out, conf.GenOptions{
+		Package:    *pkg,
+		TypeName:   *typeName,
+		ConfImport: *confImport,
+		Source:     filepath.Base(flag.Arg(0)),
+	}); err != nil {
+		fmt.Fprintln(os.Stderr, "conf-gen:", err)
+		os.Exit(1)
+	}
+}
+
+func run(path, out string, opt conf.GenOptions) error {
+	f, err := conf.NewParser(path)
+	if err != nil {
+		return err
+	}
+	if out != "" {
+		prev, err := ioutil.ReadFile(out)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		opt.Previous = prev
+	}
+	code, err := conf.Generate(f, opt)
+	if err != nil {
+		return err
+	}
+	if out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return ioutil.WriteFile(out, code, 0644)
+}
@@ -0,0 +1,222 @@
+// conf-secret 生成密钥，加密、解密以及轮换ini文件中enc:v1:形式的值
+//
+//	conf-secret genkey [-o secret.key]
+//	conf-secret encrypt [-key secret.key] [-file spider.ini] section::key... | value...
+//	conf-secret decrypt [-key secret.key] [-file spider.ini] section::key... | value...
+//	conf-secret rotate [-key secret.key] -new-key new.key -file spider.ini
+//
+// 没有指定-key时从环境变量CONF_SECRET_KEY或CONF_SECRET_KEY_FILE中读取密钥
+package main
+
+import (
+	"bufio"
+	"conf"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+const usage = `usage: conf-secret <command> [flags] [args]
+
+commands:
+  genkey   生成新的密钥
+  encrypt  加密参数中的字符串，指定-file时加密文件中的键并写回文件
+  decrypt  解密参数中的字符串，指定-file时输出文件中的键解密后的值
+  rotate   使用-new-key重新加密文件以及include的文件中所有加密的值并写回
+`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	var err error
+	switch cmd, args := os.Args[1], os.Args[2:]; cmd {
+	case "genkey":
+		err = genkey(args)
+	case "encrypt":
+		err = encrypt(args)
+	case "decrypt":
+		err = decrypt(args)
+	case "rotate":
+		err = rotate(args)
+	case "help", "-h", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %s\n%s", cmd, usage)
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "conf-secret:", err)
+		os.Exit(1)
+	}
+}
+
+func genkey(args []string) error {
+	fs := flag.NewFlagSet("genkey", flag.ExitOnError)
+	out := fs.String("o", "", "写入的密钥文件，默认输出到标准输出")
+	fs.Parse(args)
+	key, err := conf.NewSecretKey()
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		fmt.Println(key)
+		return nil
+	}
+	return ioutil.WriteFile(*out, []byte(key+"\n"), 0600)
+}
+
+func readKey(path string) ([]byte, error) {
+	if path == "" {
+		return conf.DefaultSecretKey()
+	}
+	return conf.ReadSecretKey(path)
+}
+
+// values 没有参数时从标准输入按行读取
+func values(args []string) ([]string, error) {
+	if len(args) > 0 {
+		return args, nil
+	}
+	var res []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		res = append(res, scanner.Text())
+	}
+	return res, scanner.Err()
+}
+
+func open(file string, key []byte) (*conf.FileReader, error) {
+	f, err := conf.NewParser(file)
+	if err != nil {
+		return nil, err
+	}
+	return f, f.SetSecretKey(key)
+}
+
+func encrypt(args []string) error {
+	fs := flag.NewFlagSet("encrypt", flag.ExitOnError)
+	keyFile := fs.String("key", "", "密钥文件")
+	file := fs.String("file", "", "需要加密的ini文件，参数为section::key")
+	fs.Parse(args)
+	key, err := readKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		items, err := values(fs.Args())
+		if err != nil {
+			return err
+		}
+		for _, v := range items {
+			enc, err := conf.Encrypt(key, v)
+			if err != nil {
+				return err
+			}
+			fmt.Println(enc)
+		}
+		return nil
+	}
+
+	f, err := open(*file, key)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("no key to encrypt")
+	}
+	for _, name := range fs.Args() {
+		// 加密文件中的原始值，值来自include的文件时需要对那个文件执行encrypt
+		if err := f.EncryptKey(name); err != nil {
+			return err
+		}
+	}
+	return f.Save("")
+}
+
+func decrypt(args []string) error {
+	fs := flag.NewFlagSet("decrypt", flag.ExitOnError)
+	keyFile := fs.String("key", "", "密钥文件")
+	file := fs.String("file", "", "需要解密的ini文件，参数为section::key，文件不会被修改")
+	fs.Parse(args)
+	key, err := readKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	if *file == "" {
+		items, err := values(fs.Args())
+		if err != nil {
+			return err
+		}
+		for _, v := range items {
+			plain, err := conf.Decrypt(key, strings.TrimSpace(v))
+			if err != nil {
+				return err
+			}
+			fmt.Println(plain)
+		}
+		return nil
+	}
+
+	f, err := open(*file, key)
+	if err != nil {
+		return err
+	}
+	for _, name := range fs.Args() {
+		c, ok := f.Lookup(name)
+		if !ok {
+			return fmt.Errorf("%s: no such key or decrypt failed", name)
+		}
+		fmt.Printf("%s = %s\n", name, c.GetAsString())
+	}
+	return nil
+}
+
+func rotate(args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	keyFile := fs.String("key", "", "当前的密钥文件")
+	newKeyFile := fs.String("new-key", "", "新的密钥文件")
+	file := fs.String("file", "", "需要重新加密的ini文件")
+	fs.Parse(args)
+	if *newKeyFile == "" || *file == "" {
+		return errors.New("rotate requires -new-key and -file")
+	}
+	key, err := readKey(*keyFile)
+	if err != nil {
+		return err
+	}
+	newKey, err := conf.ReadSecretKey(*newKeyFile)
+	if err != nil {
+		return err
+	}
+	f, err := open(*file, key)
+	if err != nil {
+		return err
+	}
+	// 分别轮换file以及include的文件中的密文，全部轮换成功后再写回
+	files := f.SecretFiles()
+	readers := make([]*conf.FileReader, len(files))
+	counts := make([]int, len(files))
+	for i, name := range files {
+		if readers[i], err = open(name, key); err != nil {
+			return err
+		}
+		if counts[i], err = readers[i].Rotate(newKey); err != nil {
+			return err
+		}
+	}
+	for i, name := range files {
+		if err := readers[i].Save(""); err != nil {
+			return err
+		}
+		fmt.Printf("rotated %d values in %s\n", counts[i], name)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"conf"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "conf-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func writeKey(t *testing.T, path string) []byte {
+	if err := genkey([]string{"-o", path}); err != nil {
+		t.Fatal(err)
+	}
+	key, err := conf.ReadSecretKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncrypt_JSON(t *testing.T) {
+	const content = `{"db": {"password": "admin123"}}`
+	dir := writeFiles(t, map[string]string{"spider.json": content})
+	defer os.RemoveAll(dir)
+	keyFile := filepath.Join(dir, "secret.key")
+	writeKey(t, keyFile)
+
+	path := filepath.Join(dir, "spider.json")
+	if err := encrypt([]string{"-key", keyFile, "-file", path, "db::password"}); err == nil {
+		t.Error("expect error for json file")
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != content {
+		t.Errorf("json file changed:\n%s", data)
+	}
+}
+
+func TestRotate_Include(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spider.ini":  "%include secrets.ini\n\n[app]\ndsn = root:${db::password}@127.0.0.1\n",
+		"secrets.ini": "[db]\npassword = admin123\n",
+	})
+	defer os.RemoveAll(dir)
+	keyFile, newKeyFile := filepath.Join(dir, "secret.key"), filepath.Join(dir, "new.key")
+	writeKey(t, keyFile)
+	newKey := writeKey(t, newKeyFile)
+
+	path, secrets := filepath.Join(dir, "spider.ini"), filepath.Join(dir, "secrets.ini")
+	if err := encrypt([]string{"-key", keyFile, "-file", path, "db::password"}); err == nil {
+		t.Error("expect error for included key")
+	}
+	if err := encrypt([]string{"-key", keyFile, "-file", secrets, "db::password"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := encrypt([]string{"-key", keyFile, "-file", path, "app::dsn"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := rotate([]string{"-key", keyFile, "-new-key", newKeyFile, "-file", path}); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "[db]") {
+		t.Errorf("included value written to spider.ini:\n%s", data)
+	}
+	f, err := conf.NewParser(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetSecretKey(newKey)
+	if v := f.Get("db::password").GetAsString(); v != "admin123" {
+		t.Errorf("expect admin123 got %s", v)
+	}
+	// dsn加密的是插值之前的原始值
+	enc := strings.TrimSpace(strings.SplitN(string(data), "dsn = ", 2)[1])
+	if plain, err := conf.Decrypt(newKey, enc); err != nil || plain != "root:${db::password}@127.0.0.1" {
+		t.Errorf("got %s %v", plain, err)
+	}
+}
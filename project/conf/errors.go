@@ -0,0 +1,71 @@
+package conf
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Mode uint8
+
+const (
+	// Lenient 重复的键、段落之外的键以及格式错误的段落名只记录为警告
+	Lenient Mode = iota
+	// Strict 以上情况都作为错误返回
+	Strict
+)
+
+// ParseError 解析错误，Line和Column从1开始
+type ParseError struct {
+	File   string
+	Line   uint64
+	Column int
+	Text   string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %q", e.File, e.Line, e.Column, e.Reason, e.Text)
+}
+
+// ParseErrors 一次解析中的所有错误
+type ParseErrors []*ParseError
+
+func (e ParseErrors) Error() string {
+	msg := make([]string, len(e))
+	for i, err := range e {
+		msg[i] = err.Error()
+	}
+	return strings.Join(msg, "\n")
+}
+
+func (f *FileReader) SetMode(mode Mode) {
+	if f.root != nil {
+		f.root.SetMode(mode)
+		return
+	}
+	f.mode = mode
+}
+
+// Warnings 宽松模式下解析时产生的警告
+func (f *FileReader) Warnings() ParseErrors {
+	if f.root != nil {
+		return f.root.Warnings()
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.warnings
+}
+
+func (f *FileReader) newError(column int, reason string) *ParseError {
+	return &ParseError{File: f.fileName, Line: f.lineNo, Column: column, Text: f.currentLine, Reason: reason}
+}
+
+// strictError 严格模式下作为错误返回，否则只记录为警告
+func (f *FileReader) strictError(errs ParseErrors, column int, reason string) ParseErrors {
+	e := f.newError(column, reason)
+	if f.mode == Strict {
+		return append(errs, e)
+	}
+	f.warnings = append(f.warnings, e)
+	return errs
+}
@@ -0,0 +1,75 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const badIni = `name = spider
+[user
+username = admin
+username = root
+  port 80
+[administrator]
+`
+
+func parseTemp(t *testing.T, content string, mode Mode) (*FileReader, error) {
+	dir := writeFiles(t, map[string]string{"test.ini": content})
+	defer os.RemoveAll(dir)
+	f := &FileReader{}
+	f.SetMode(mode)
+	if err := f.Open(filepath.Join(dir, "test.ini")); err != nil {
+		t.Fatal(err)
+	}
+	return f, f.Parser()
+}
+
+func TestFileReader_ParserLenient(t *testing.T) {
+	f, err := parseTemp(t, badIni, Lenient)
+	errs, ok := err.(ParseErrors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("expect 1 error got %v", err)
+	}
+	if e := errs[0]; e.Line != 5 || e.Column != 3 || e.Text != "  port 80" {
+		t.Errorf("got %s", e)
+	}
+
+	warnings := f.Warnings()
+	expect := []struct {
+		line   uint64
+		column int
+	}{{1, 1}, {2, 6}, {4, 1}}
+	if len(warnings) != len(expect) {
+		t.Fatalf("expect %d warnings got %v", len(expect), warnings)
+	}
+	for i, w := range warnings {
+		if w.Line != expect[i].line || w.Column != expect[i].column {
+			t.Errorf("expect %d:%d got %s", expect[i].line, expect[i].column, w)
+		}
+	}
+	if v := f.Get("user::username").GetAsString(); v != "root" {
+		t.Errorf("expect root got %s", v)
+	}
+	if v := f.Get("::name").GetAsString(); v != "spider" {
+		t.Errorf("expect spider got %s", v)
+	}
+}
+
+func TestFileReader_ParserStrict(t *testing.T) {
+	f, err := parseTemp(t, badIni, Strict)
+	errs, ok := err.(ParseErrors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("expect 4 errors got %v", err)
+	}
+	if len(f.Warnings()) != 0 {
+		t.Error("strict mode should not produce warnings")
+	}
+	for _, e := range errs {
+		t.Log(e)
+	}
+
+	if _, err := parseTemp(t, "[user]\nusername = admin\n", Strict); err != nil {
+		t.Error(err)
+	}
+}
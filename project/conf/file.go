@@ -8,6 +8,7 @@ import (
 	"io"
 	"io/ioutil"
 	"strings"
+	"sync"
 	_ "unsafe"
 )
 
@@ -19,92 +20,269 @@ type FileReader struct {
 	lineNo      uint64
 	currentLine string
 	data        map[string]Value
+	sections    []*section // 保留原文件的段落、注释、空行顺序，用于回写
+	env         bool       // 是否允许环境变量覆盖
+	envPrefix   string
+	mu          sync.RWMutex
+	watcher     *watcher
+	origin      map[string]Origin // 每个键的来源文件和行号
+	layers      []string          // Load时覆盖在fileName之上的文件
+	includes    []string          // 正在解析的include链，用于检测循环引用
+	included    []string          // include进来的文件，包括嵌套include的文件，Watch时一起检查
+	mode        Mode
+	warnings    ParseErrors
+	ignoreCase  bool
+	root        *FileReader // Sub返回的视图指向原始的FileReader
+	prefix      string
+	defaults    map[string]string // Schema中声明的默认值
+	flags       map[string]string // 命令行参数覆盖的值，优先级最高
+	flagNames   map[string]string // 键对应的命令行参数名
+	secret      []byte            // 解密enc:v1:形式的值使用的密钥
+	loader      Loader            // 解析使用的Loader，为空时按INI处理
+}
+
+type lineKind uint8
+
+const (
+	blankLine lineKind = iota
+	commentLine
+	sectionLine
+	keyLine
+	includeLine
+)
+
+// line 原文件中的一行，text为空表示该行被修改过，需要重新生成
+type line struct {
+	kind    lineKind
+	text    string
+	key     string
+	value   string
+	comment string // 值之后的行内注释，包括之前的空白，重新生成时保留
+	lineNo  uint64
+}
+
+func (l *line) String() string {
+	if l.text != "" || l.kind != keyLine {
+		return l.text
+	}
+	if l.comment != "" {
+		return strings.TrimRight(l.key+" = "+quoteValue(l.value), " ") + l.comment
+	}
+	return l.key + " = " + quoteValue(l.value)
+}
+
+// section 名字为空的section存放第一个段落之前的注释和空行，重复出现的段落每次出现都对应一个section
+type section struct {
+	name  string
+	lines []*line
+	value Value // 只包含本次出现的段落中的键
+	table bool  // 使用[[name]]声明
 }
 
 func (f *FileReader) Open(fileName string) (err error) {
+	if f.root != nil {
+		return errSubView
+	}
 	data, err := ioutil.ReadFile(fileName)
-	f.data = make(map[string]Value)
+	f.reset()
 	if err != nil {
 		return
 	}
+	f.fileName = fileName
 	f.reader = bufio.NewReader(bytes.NewBuffer(data))
 	return nil
 }
 
-func (f *FileReader) readLine() error {
+func (f *FileReader) reset() {
+	f.data = make(map[string]Value)
+	f.sections = []*section{{value: *NewValue()}}
+	f.origin = make(map[string]Origin)
+	f.included = nil
+}
 
-	data, _, err := f.reader.ReadLine()
-	if err != nil {
+// readLine 读取一行，空行以及以#或;开头的注释行返回BOL
+func (f *FileReader) readLine() error {
+	data, err := f.reader.ReadString('\n')
+	if err != nil && (err != io.EOF || data == "") {
 		return err
 	}
-	f.currentLine = string(data)
+	f.currentLine = strings.TrimRight(data, "\r\n")
 	f.lineNo++
-	if f.currentLine == "" || len(f.currentLine) <= 0 || strings.HasPrefix(f.currentLine, "#") {
+	text := strings.TrimSpace(f.currentLine)
+	if text == "" || text[0] == '#' || text[0] == ';' {
 		return BOL
 	}
 	return nil
 }
 
+// Parser 解析整个文件，遇到错误时继续解析后面的行，最后返回所有错误的ParseErrors
 func (f *FileReader) Parser() error {
 	if f.reader == nil {
 		return errors.New("file not read")
 	}
-	var lastSpace string
-	var value *Value
+	var (
+		lastSpace string
+		value     *Value
+		errs      ParseErrors
+		seen      = make(map[string]uint64)
+	)
 
 	for {
 		err := f.readLine()
+		if err == io.EOF {
+			f.currentLine = ""
+			break
+		}
+		if err == BOL {
+			f.appendLine(f.currentLine)
+			continue
+		}
 		if err != nil {
-			if err == io.EOF {
-				f.currentLine = ""
-				return nil
+			return err
+		}
+
+		text := strings.TrimSpace(f.currentLine)
+		indent := strings.Index(f.currentLine, text)
+		if strings.HasPrefix(text, "[") {
+			header, table := stripComment(text), false
+			if strings.HasPrefix(header, "[[") && strings.HasSuffix(header, "]]") {
+				header, table = header[1:len(header)-1], true
 			}
-			if err == BOL {
-				continue
+			name, col, reason := parseSection(header)
+			if reason != "" {
+				errs = f.strictError(errs, indent+col, reason)
 			}
-			fmt.Println("error at line:", f.lineNo)
+			lastSpace = f.name(name)
+			value = f.value(lastSpace)
+			f.sections = append(f.sections, &section{
+				name:  lastSpace,
+				lines: []*line{{kind: sectionLine, text: f.currentLine}},
+				value: *NewValue(),
+				table: table,
+			})
+			continue
 		}
 
-		if f.currentLine[:1] == "[" && f.currentLine[len(f.currentLine)-1] == ']' {
-			value = NewValue()
-			lastSpace = getSpace(f.currentLine)
-		} else {
-			k, v, err := checkAndSplit(f.currentLine)
-			if err != nil {
-				fmt.Println(err)
+		if path, ok := includePath(f.currentLine, lastSpace); ok {
+			if err := f.include(path); err != nil {
+				if list, ok := err.(ParseErrors); ok {
+					errs = append(errs, list...)
+				} else {
+					errs = append(errs, f.newError(indent+1, err.Error()))
+				}
+			}
+			if value != nil {
+				value = f.value(lastSpace)
 			}
-			value.Put(k, v)
-			f.data[lastSpace] = *value
+			last := f.sections[len(f.sections)-1]
+			last.lines = append(last.lines, &line{kind: includeLine, text: f.currentLine})
+			continue
+		}
+
+		k, raw, col, err := checkAndSplit(f.currentLine)
+		if err != nil {
+			errs = append(errs, f.newError(col, err.Error()))
+			f.appendLine(f.currentLine)
+			continue
 		}
+		k = f.name(k)
+		lineNo, valueCol := f.lineNo, strings.Index(f.currentLine, "=")+2
+		valueCol += strings.Index(f.currentLine[valueCol-1:], raw)
+		v, full, col, err := f.readValue(raw)
+		if err != nil {
+			errs = append(errs, f.newError(valueCol+col, err.Error()))
+			f.appendLine(full)
+			continue
+		}
+		if value == nil {
+			errs = f.strictError(errs, indent+1, "key outside of section")
+			value = f.value(lastSpace)
+		}
+		occurrence := fmt.Sprintf("%d::%s", len(f.sections), k)
+		if no, ok := seen[occurrence]; ok {
+			errs = f.strictError(errs, indent+1, fmt.Sprintf("duplicate key %s, first defined at line %d", k, no))
+		}
+		seen[occurrence] = lineNo
+
+		value.Put(k, v)
+		f.data[lastSpace] = *value
+		f.origin[lastSpace+"::"+k] = Origin{File: f.fileName, Line: lineNo}
+		last := f.sections[len(f.sections)-1]
+		last.value.Put(k, v)
+		last.lines = append(last.lines, &line{kind: keyLine, text: full, key: k, value: v, comment: valueComment(raw, full), lineNo: lineNo})
+	}
+
+	f.indexTables("")
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// parseSection 解析段落名，格式错误时尽量给出一个可用的名字，并返回出错的列和原因
+func parseSection(text string) (name string, col int, reason string) {
+	if !strings.HasSuffix(text, "]") {
+		return strings.TrimSpace(strings.TrimLeft(text, "[")), len(text) + 1, "missing ']' in section header"
+	}
+	name = strings.TrimSpace(text[1 : len(text)-1])
+	if idx := strings.IndexAny(name, "[]"); idx >= 0 {
+		return strings.TrimSpace(strings.Trim(name, "[]")), strings.Index(text, name) + idx + 1, "unexpected bracket in section name"
+	}
+	if name == "" {
+		return "", 2, "empty section name"
 	}
+	return name, 0, ""
+}
+
+// value 返回段落对应的Value，不存在时创建，重复的段落以及include进来的段落会合并到一起
+func (f *FileReader) value(name string) *Value {
+	v, ok := f.data[name]
+	if !ok {
+		v = *NewValue()
+		f.data[name] = v
+	}
+	return &v
+}
+
+func (f *FileReader) appendLine(text string) {
+	kind := blankLine
+	if strings.TrimSpace(text) != "" {
+		kind = commentLine
+	}
+	last := f.sections[len(f.sections)-1]
+	last.lines = append(last.lines, &line{kind: kind, text: text})
 }
 
 func (f *FileReader) Get(str string) *conv {
 	data := strings.Split(str, "::")
-	if len(data) == 0 || len(data) > 2 {
+	if len(data) != 2 {
 		panic(errors.New("syntax error"))
 	}
-	value, ok := f.data[data[0]]
-	if !ok {
-		panic(fmt.Sprintf("no such key %s", str))
+	d, ok, err := f.lookup(data[0], data[1])
+	if err != nil {
+		panic(err)
 	}
-	if d, ok := value.Get(data[1]); ok {
+	if ok {
 		return d
 	}
 	panic(fmt.Sprintf("no such key %s", str))
 }
 
-func checkAndSplit(line string) (k, v string, err error) {
-	if line == "" {
-		return "", "", errors.New("black line")
+// checkAndSplit 按第一个'='拆分"key = value"，v为未经处理的值，出错时col为出错的列
+func checkAndSplit(line string) (k, v string, col int, err error) {
+	if strings.TrimSpace(line) == "" {
+		return "", "", 1, errors.New("black line")
 	}
-	data := strings.Split(strings.TrimSpace(line), "=")
-	if len(data) != 2 {
-		return "", "", errors.New("syntax error '=' in one line")
+	idx := strings.Index(line, "=")
+	if idx < 0 {
+		return "", "", len(line) - len(strings.TrimLeft(line, " \t")) + 1, errors.New("missing '=' in key line")
+	}
+	k = strings.TrimSpace(line[:idx])
+	v = strings.TrimSpace(line[idx+1:])
+	if k == "" {
+		return "", "", idx + 1, errors.New("empty key")
 	}
-	k = strings.TrimSpace(data[0])
-	v = strings.TrimSpace(data[1])
-	return
+	return k, v, 0, nil
 }
 
 func getSpace(data string) string {
@@ -1,27 +1,28 @@
 package conf
 
 import (
-	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 )
 
 func TestReadFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"test.ini": "[tet]\nusername = venmosnake\n"})
+	defer os.RemoveAll(dir)
+
 	f := FileReader{}
-	err := f.Open("/Users/venmosnake/Documents/go/test.ini")
+	err := f.Open(filepath.Join(dir, "test.ini"))
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
 	err = f.Parser()
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
 
-	v,err := f.Get("tet::username")
-	if err != nil {
-		t.Error(err)
+	v := f.Get("tet::username")
+	if v.GetAsString() != "venmosnake" {
+		t.Errorf("got %s", v.GetAsString())
 	}
-
-	fmt.Println(v.GetAsString())
-
 }
@@ -0,0 +1,286 @@
+package conf
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// FlagName 键对应的命令行参数名，形如"section.key"，第一个段落之前的键直接使用键名
+func FlagName(section, key string) string {
+	if section == "" {
+		return key
+	}
+	return section + "." + key
+}
+
+// flagValue 实现flag.Value，Set时直接把值覆盖到FileReader中
+type flagValue struct {
+	f       *FileReader
+	section string
+	key     string
+	def     string
+	rule    *Rule
+	value   *string
+}
+
+func (v *flagValue) String() string {
+	if v == nil || v.f == nil {
+		return ""
+	}
+	if v.value != nil {
+		return *v.value
+	}
+	return v.def
+}
+
+func (v *flagValue) Set(s string) error {
+	if v.rule != nil {
+		if reasons := v.rule.check(s); len(reasons) > 0 {
+			return errors.New(strings.Join(reasons, ", "))
+		}
+	}
+	v.value = &s
+	v.f.setFlag(v.section, v.key, s)
+	return nil
+}
+
+// IsBoolFlag Schema中声明为TypeBool的键可以只写"--section.key"
+func (v *flagValue) IsBoolFlag() bool {
+	return v.rule != nil && v.rule.typ == TypeBool
+}
+
+func (f *FileReader) setFlag(section, key, value string) {
+	if f.root != nil {
+		f.root.setFlag(f.scoped(section), key, value)
+		return
+	}
+	section, key = f.name(section), f.name(key)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.flags == nil {
+		f.flags = make(map[string]string)
+		f.flagNames = make(map[string]string)
+	}
+	f.flags[section+"::"+key] = value
+}
+
+func (f *FileReader) setFlagName(section, key, name string) {
+	if f.root != nil {
+		f.root.setFlagName(f.scoped(section), key, name)
+		return
+	}
+	section, key = f.name(section), f.name(key)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.flagNames == nil {
+		f.flags = make(map[string]string)
+		f.flagNames = make(map[string]string)
+	}
+	f.flagNames[section+"::"+key] = name
+}
+
+// BindFlags 为文件中的每个键以及schema中声明的键在fs中注册"--section.key"参数，schema可以为nil
+// 参数的默认值为当前生效的值，fs.Parse时显式设置的参数覆盖文件和环境变量中的值，
+// 声明过的键在解析参数时按照Schema中的规则校验，fs中已经存在的同名参数会被跳过
+func (f *FileReader) BindFlags(fs *flag.FlagSet, schema *Schema) {
+	rules := make(map[string]*Rule)
+	var keys [][2]string
+	f.Range(func(section, key string, value *conv) bool {
+		keys = append(keys, [2]string{section, key})
+		return true
+	})
+	if schema != nil {
+		for _, r := range schema.rules {
+			space, key, err := splitKey(r.key)
+			if err != nil {
+				continue
+			}
+			name := f.name(space) + "::" + f.name(key)
+			if _, ok := rules[name]; !ok {
+				keys = append(keys, [2]string{space, key})
+			}
+			rules[name] = r
+		}
+	}
+
+	for _, k := range keys {
+		section, key := k[0], k[1]
+		name := FlagName(section, key)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		v := &flagValue{f: f, section: section, key: key, rule: rules[f.name(section)+"::"+f.name(key)]}
+		usage := "string"
+		if v.rule != nil {
+			usage = v.rule.typ.String()
+			if v.rule.required {
+				usage += ", required"
+			}
+		}
+		if str, ok := f.sealed(section, key); ok {
+			v.def = str
+		} else if c, ok, err := f.lookup(section, key); err == nil && ok {
+			v.def = fmt.Sprint(c.data)
+		} else if v.rule != nil && v.rule.def != nil {
+			v.def = *v.rule.def
+		}
+		if src, ok := f.Source(section + "::" + key); ok {
+			usage += " (" + src + ")"
+		}
+		f.setFlagName(section, key, name)
+		fs.Var(v, name, usage)
+	}
+}
+
+// ParseFlags 绑定参数后解析args，相当于BindFlags加fs.Parse
+func (f *FileReader) ParseFlags(fs *flag.FlagSet, args []string, schema *Schema) error {
+	f.BindFlags(fs, schema)
+	return fs.Parse(args)
+}
+
+// Source 描述"section::key"当前生效的值的来源，如"flag --spider.workers"、"env SPIDER_WORKERS"、
+// "spider.ini:12"或"default"
+func (f *FileReader) Source(str string) (string, bool) {
+	space, key, err := splitKey(str)
+	if err != nil {
+		return "", false
+	}
+	if f.root != nil {
+		return f.root.Source(f.scoped(space) + "::" + key)
+	}
+	_, src, ok := f.find(space, key)
+	return src, ok
+}
+
+// overlayKeys 只存在于命令行参数和默认值中，文件里没有的键
+func (f *FileReader) overlayKeys() [][2]string {
+	root := f
+	if f.root != nil {
+		root = f.root
+	}
+	root.mu.RLock()
+	names := make(map[string]bool)
+	for name := range root.flags {
+		names[name] = true
+	}
+	for name := range root.defaults {
+		names[name] = true
+	}
+	root.mu.RUnlock()
+
+	var res [][2]string
+	for name := range names {
+		space, key, err := splitKey(name)
+		if err != nil {
+			continue
+		}
+		if f.root != nil {
+			if space == f.prefix {
+				space = ""
+			} else if strings.HasPrefix(space, f.prefix+".") {
+				space = space[len(f.prefix)+1:]
+			} else {
+				continue
+			}
+		}
+		res = append(res, [2]string{space, key})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i][0] != res[j][0] {
+			return res[i][0] < res[j][0]
+		}
+		return res[i][1] < res[j][1]
+	})
+	return res
+}
+
+// Dump 以INI格式输出所有生效的值，每个值后面用注释标明来源，加密的值按密文输出，
+// 重复出现的段落按[[name]]输出，输出的内容可以重新解析
+func (f *FileReader) Dump(w io.Writer) error {
+	var order []string
+	keys := make(map[string][]string)
+	seen := make(map[string]bool)
+	from := make(map[string]string) // 没有下标的键输出到表格的元素中，查找时仍然使用原来的段落
+	add := func(section, key string) {
+		if _, _, ok := tableIndex(section); !ok && f.tableDeclared(section) {
+			// 没有下标的键属于最后一个元素，与Get的结果保持一致
+			table := tableName(section, len(f.Tables(section))-1)
+			from[table+"::"+key] = section
+			section = table
+		}
+		name := f.name(section) + "::" + f.name(key)
+		if seen[name] {
+			return
+		}
+		seen[name] = true
+		if _, ok := keys[section]; !ok {
+			if base, _, ok := tableIndex(section); ok {
+				// 表格的所有元素按顺序输出，没有键的元素也要输出，保证下标不变
+				for i := range f.Tables(base) {
+					if _, ok := keys[tableName(base, i)]; !ok {
+						order = append(order, tableName(base, i))
+						keys[tableName(base, i)] = nil
+					}
+				}
+			} else {
+				order = append(order, section)
+			}
+		}
+		keys[section] = append(keys[section], key)
+	}
+	f.Range(func(section, key string, value *conv) bool {
+		add(section, key)
+		return true
+	})
+	for _, k := range f.overlayKeys() {
+		add(k[0], k[1])
+	}
+	// 第一个段落之前的键必须最先输出
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i] == "" && order[j] != ""
+	})
+
+	for i, section := range order {
+		if section != "" {
+			if i > 0 {
+				if _, err := io.WriteString(w, "\n"); err != nil {
+					return err
+				}
+			}
+			header := "[" + section + "]"
+			if base, _, ok := tableIndex(section); ok {
+				// 表格的每个元素输出为[[name]]，输出的内容可以重新解析
+				header = "[[" + base + "]]"
+			}
+			if _, err := fmt.Fprintln(w, header); err != nil {
+				return err
+			}
+		}
+		for _, key := range keys[section] {
+			space := section
+			if s, ok := from[section+"::"+key]; ok {
+				space = s
+			}
+			value, ok := f.sealed(space, key)
+			if !ok {
+				c, found, err := f.lookup(space, key)
+				if err != nil {
+					return err
+				}
+				if !found {
+					continue
+				}
+				value = fmt.Sprint(c.data)
+			}
+			src, _ := f.Source(space + "::" + key)
+			if _, err := fmt.Fprintf(w, "%s = %s # %s\n", key, quoteValue(value), src); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
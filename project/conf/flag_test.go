@@ -0,0 +1,148 @@
+package conf
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const flagIni = `name = spider
+[spider]
+workers = 4
+debug = false
+[spider.downloader]
+timeout = 10s
+`
+
+func TestFileReader_BindFlags(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"spider.ini": flagIni})
+	defer os.RemoveAll(dir)
+	f, err := NewParser(filepath.Join(dir, "spider.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("FLAGTEST_SPIDER_WORKERS", "6")
+	defer os.Unsetenv("FLAGTEST_SPIDER_WORKERS")
+	f.EnvOverride("FLAGTEST_")
+
+	schema := NewSchema()
+	schema.Key("spider::workers", TypeInt).Range(1, 32)
+	schema.Key("spider::debug", TypeBool)
+	schema.Key("spider::retry", TypeInt).Default("3")
+
+	fs := flag.NewFlagSet("spider", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	f.BindFlags(fs, schema)
+	for _, name := range []string{"name", "spider.workers", "spider.debug", "spider.downloader.timeout", "spider.retry"} {
+		if fs.Lookup(name) == nil {
+			t.Fatalf("flag %s not registered", name)
+		}
+	}
+	if v := fs.Lookup("spider.workers").DefValue; v != "6" {
+		t.Errorf("expect 6 got %s", v)
+	}
+
+	if err := f.ParseFlags(fs, []string{"--spider.workers=64"}, schema); err == nil {
+		t.Error("expect range error")
+	}
+	if err := fs.Parse([]string{"--spider.workers", "8", "--spider.debug", "run"}); err != nil {
+		t.Fatal(err)
+	}
+	if fs.Arg(0) != "run" {
+		t.Errorf("got %v", fs.Args())
+	}
+	if v := f.Get("spider::workers").GetAsInt(); v != 8 {
+		t.Errorf("flag should override env, got %d", v)
+	}
+	if v, _ := f.Get("spider::debug").GetAsBool(); !v {
+		t.Error("expect true")
+	}
+	// 子段落继承参数覆盖后的值
+	if v := f.Get("spider.downloader::workers").GetAsInt(); v != 8 {
+		t.Errorf("expect 8 got %d", v)
+	}
+	if err := schema.Validate(f); err != nil {
+		t.Fatal(err)
+	}
+
+	expect := map[string]string{
+		"spider::workers":            "flag --spider.workers",
+		"::name":                     filepath.Join(dir, "spider.ini") + ":1",
+		"spider.downloader::timeout": filepath.Join(dir, "spider.ini") + ":6",
+		"spider::retry":              "default",
+	}
+	for key, src := range expect {
+		if got, _ := f.Source(key); got != src {
+			t.Errorf("%s: expect %s got %s", key, src, got)
+		}
+	}
+	if _, ok := f.Source("spider::missing"); ok {
+		t.Error("expect missing")
+	}
+
+	// 重新加载文件后参数仍然生效
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("spider::workers").GetAsInt(); v != 8 {
+		t.Errorf("expect 8 got %d", v)
+	}
+}
+
+func TestFileReader_Dump(t *testing.T) {
+	f, err := ParseString(flagIni, INI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fs := flag.NewFlagSet("spider", flag.ContinueOnError)
+	if err := f.ParseFlags(fs, []string{"--spider.workers=8"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	f.setDefault("spider", "retry", "3")
+
+	var buf bytes.Buffer
+	if err := f.Sub("spider").Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	expect := "workers = 8 # flag --spider.workers\ndebug = false # :4\nretry = 3 # default\n\n" +
+		"[downloader]\ntimeout = 10s # :6\n"
+	if buf.String() != expect {
+		t.Errorf("got\n%s", buf.String())
+	}
+}
+
+// 表格输出为[[name]]，输出的内容可以重新解析
+func TestFileReader_DumpTables(t *testing.T) {
+	f, err := ParseString(tableIni+"\n[[empty]]\n\n[[empty]]\nname = b\n", INI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.setDefault("seed", "depth", "2")
+
+	var buf bytes.Buffer
+	if err := f.Dump(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "[seed[") || !strings.Contains(buf.String(), "[[downloader]]\n") {
+		t.Errorf("got\n%s", buf.String())
+	}
+	dumped, err := ParseString(buf.String(), INI, WithMode(Strict))
+	if err != nil {
+		t.Fatalf("%v\n%s", err, buf.String())
+	}
+	for key, want := range map[string]string{
+		"downloader[0]::timeout": "10s", "downloader[1]::retry": "3", "seed[0]::url": "http://a.com",
+		"seed[1]::url": "http://b.com", "seed::depth": "2", "empty[1]::name": "b",
+	} {
+		if v, ok := dumped.Lookup(key); !ok || v.GetAsString() != want {
+			t.Errorf("%s: expect %s got %v", key, want, v)
+		}
+	}
+	if n := len(dumped.Tables("empty")); n != 2 {
+		t.Errorf("expect 2 empty tables got %d", n)
+	}
+}
@@ -0,0 +1,482 @@
+package conf
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	gofmt "go/format"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// InferType 按照conv.GetAs*的转换规则推断值的类型，依次尝试数组、整数、浮点数、布尔值、时间间隔、时间、网段、IP、URL和大小，
+// 都不符合时为TypeString
+func InferType(v string) Type {
+	v = strings.TrimSpace(v)
+	if v == "" || IsEncrypted(v) {
+		return TypeString
+	}
+	if strings.HasPrefix(v, "[") && strings.HasSuffix(v, "]") {
+		typ := TypeIntSlice
+		for _, item := range splitSlice(v) {
+			switch InferType(item) {
+			case TypeInt:
+			case TypeFloat:
+				typ = TypeFloatSlice
+			default:
+				return TypeStringSlice
+			}
+		}
+		if len(splitSlice(v)) == 0 {
+			return TypeStringSlice
+		}
+		return typ
+	}
+	c := &conv{data: v}
+	if _, err := c.GetAsInt64(); err == nil {
+		return TypeInt
+	}
+	if _, err := c.GetAsFloat64(); err == nil {
+		return TypeFloat
+	}
+	if _, err := c.GetAsBool(); err == nil {
+		return TypeBool
+	}
+	if _, err := c.GetAsDuration(); err == nil {
+		return TypeDuration
+	}
+	if _, err := c.GetAsTime(); err == nil {
+		return TypeTime
+	}
+	if _, err := c.GetAsCIDR(); err == nil {
+		return TypeCIDR
+	}
+	if _, err := c.GetAsIP(); err == nil {
+		return TypeIP
+	}
+	if _, err := c.GetAsURL(); err == nil {
+		return TypeURL
+	}
+	if _, err := c.GetAsSize(); err == nil {
+		return TypeSize
+	}
+	return TypeString
+}
+
+// unifyType 同一个键在重复的段落中推断出不同的类型时使用能够同时表示两者的类型
+func unifyType(a, b Type) Type {
+	switch {
+	case a == b:
+		return a
+	case a == TypeInt && b == TypeFloat || a == TypeFloat && b == TypeInt:
+		return TypeFloat
+	case a == TypeInt && b == TypeSize || a == TypeSize && b == TypeInt:
+		return TypeSize
+	case a == TypeIntSlice && b == TypeFloatSlice || a == TypeFloatSlice && b == TypeIntSlice:
+		return TypeFloatSlice
+	case isSliceType(a) && isSliceType(b):
+		return TypeStringSlice
+	}
+	return TypeString
+}
+
+func isSliceType(t Type) bool {
+	return t == TypeIntSlice || t == TypeFloatSlice || t == TypeStringSlice
+}
+
+var goTypes = map[Type]string{
+	TypeString:      "string",
+	TypeInt:         "int",
+	TypeFloat:       "float64",
+	TypeBool:        "bool",
+	TypeIntSlice:    "[]int",
+	TypeFloatSlice:  "[]float64",
+	TypeStringSlice: "[]string",
+	TypeDuration:    "time.Duration",
+	TypeSize:        "int64",
+	TypeTime:        "time.Time",
+	TypeURL:         "*url.URL",
+	TypeIP:          "net.IP",
+	TypeCIDR:        "*net.IPNet",
+}
+
+var goImports = map[Type]string{
+	TypeDuration: "time",
+	TypeTime:     "time",
+	TypeURL:      "net/url",
+	TypeIP:       "net",
+	TypeCIDR:     "net",
+}
+
+// GenOptions Generate的选项，零值表示使用默认值
+type GenOptions struct {
+	Package    string  // 生成代码的包名，默认为config
+	TypeName   string  // 顶层结构体的名称，默认为Config
+	ConfImport string  // conf包的导入路径，默认为conf
+	Source     string  // 写在文件头部注释中的来源文件
+	Schema     *Schema // Schema中声明的类型覆盖推断出的类型，文件中没有的键也会生成字段
+	Previous   []byte  // 上一次生成的代码，已有的段落和键沿用其中的类型名和字段名
+}
+
+type genStruct struct {
+	path   string
+	name   string
+	fields []*genField
+	index  map[string]*genField
+}
+
+type genField struct {
+	name   string
+	tag    string
+	typ    Type
+	known  bool // 是否已经推断出类型，空值不参与推断
+	fixed  bool // 类型由Schema声明
+	isMap  bool
+	table  bool
+	child  *genStruct
+}
+
+type generator struct {
+	opt     GenOptions
+	structs []*genStruct
+	paths   map[string]*genStruct
+	prev    previous
+}
+
+// Generate 根据f中的段落和键生成带有`ini`标签的结构体以及加载函数，
+// 段落对应结构体类型的字段，"a.b"形式的子段落嵌套在a中，重复的段落对应结构体切片，"name.sub"形式的键对应map[string]string
+func Generate(f *FileReader, opt GenOptions) ([]byte, error) {
+	if opt.Package == "" {
+		opt.Package = "config"
+	}
+	if opt.TypeName == "" {
+		opt.TypeName = "Config"
+	}
+	if opt.ConfImport == "" {
+		opt.ConfImport = "conf"
+	}
+	g := &generator{opt: opt, paths: make(map[string]*genStruct)}
+	if len(opt.Previous) > 0 {
+		prev, err := parsePrevious(opt.Previous, opt.TypeName)
+		if err != nil {
+			return nil, fmt.Errorf("parse previous code: %v", err)
+		}
+		g.prev = prev
+	}
+	g.structFor("", false)
+
+	f.each(func(space, key string) bool {
+		field := g.field(space, key)
+		if field.fixed || field.isMap {
+			return true
+		}
+		var value string
+		if str, ok := f.sealed(space, key); ok {
+			value = str
+		} else if c, ok, err := f.lookup(space, key); err == nil && ok {
+			value = fmt.Sprint(c.data)
+		}
+		if strings.TrimSpace(value) == "" {
+			return true
+		}
+		typ := InferType(value)
+		if field.known {
+			typ = unifyType(field.typ, typ)
+		}
+		field.typ, field.known = typ, true
+		return true
+	})
+	if opt.Schema != nil {
+		for _, r := range opt.Schema.rules {
+			space, key, err := splitKey(r.key)
+			if err != nil {
+				return nil, err
+			}
+			field := g.field(space, key)
+			if field.isMap {
+				return nil, fmt.Errorf("%s: schema type conflicts with map key", r.key)
+			}
+			field.typ, field.known, field.fixed = r.typ, true, true
+		}
+	}
+
+	g.assignNames()
+	return g.emit()
+}
+
+// structFor 返回段落对应的结构体，不存在时同时创建上一级段落的结构体
+func (g *generator) structFor(path string, table bool) *genStruct {
+	if s, ok := g.paths[path]; ok {
+		if table {
+			g.parentField(path).table = true
+		}
+		return s
+	}
+	s := &genStruct{path: path, index: make(map[string]*genField)}
+	g.paths[path] = s
+	g.structs = append(g.structs, s)
+	if path == "" {
+		return s
+	}
+	parent, tag := g.structFor("", false), path
+	if p, ok := parentSection(path); ok {
+		parent, tag = g.structFor(p, false), path[len(p)+1:]
+	}
+	field := parent.add(tag)
+	field.child, field.table = s, table
+	return s
+}
+
+func (g *generator) parentField(path string) *genField {
+	if p, ok := parentSection(path); ok {
+		return g.paths[p].index[path[len(p)+1:]]
+	}
+	return g.paths[""].index[path]
+}
+
+func (s *genStruct) add(tag string) *genField {
+	if field, ok := s.index[tag]; ok {
+		return field
+	}
+	field := &genField{tag: tag}
+	s.index[tag] = field
+	s.fields = append(s.fields, field)
+	return field
+}
+
+func (g *generator) field(space, key string) *genField {
+	base, _, indexed := tableIndex(space)
+	s := g.structFor(base, indexed)
+	if idx := strings.Index(key, "."); idx > 0 {
+		field := s.add(key[:idx])
+		field.isMap = true
+		return field
+	}
+	return s.add(key)
+}
+
+// assignNames 优先沿用上一次生成的名称，其余的名称按出现的顺序生成，重名时添加数字后缀
+func (g *generator) assignNames() {
+	types := map[string]bool{g.opt.TypeName: true}
+	for _, s := range g.structs {
+		if s.path == "" {
+			s.name = g.opt.TypeName
+			continue
+		}
+		if name, ok := g.prev.types[s.path]; ok && !types[name] {
+			s.name = name
+			types[name] = true
+		}
+	}
+	for _, s := range g.structs {
+		if s.name == "" {
+			s.name = unique(exportedName(s.path), types)
+		}
+	}
+
+	for _, s := range g.structs {
+		names := make(map[string]bool)
+		for _, field := range s.fields {
+			if name, ok := g.prev.fields[s.path+"::"+field.tag]; ok && !names[name] {
+				field.name = name
+				names[name] = true
+			}
+		}
+		for _, field := range s.fields {
+			if field.name == "" {
+				field.name = unique(exportedName(field.tag), names)
+			}
+		}
+	}
+}
+
+func unique(name string, used map[string]bool) string {
+	res := name
+	for i := 2; used[res]; i++ {
+		res = name + strconv.Itoa(i)
+	}
+	used[res] = true
+	return res
+}
+
+var initialisms = map[string]bool{
+	"API": true, "CPU": true, "DNS": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true,
+	"JSON": true, "SQL": true, "SSH": true, "TCP": true, "TLS": true, "TTL": true, "UDP": true,
+	"URI": true, "URL": true,
+}
+
+// exportedName 按非字母数字的字符拆分后首字母大写，例如"user-agent"为UserAgent，"needInit"为NeedInit，"proxy_url"为ProxyURL
+func exportedName(s string) string {
+	var buf strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if upper := strings.ToUpper(part); initialisms[upper] {
+			buf.WriteString(upper)
+			continue
+		}
+		runes := []rune(part)
+		runes[0] = unicode.ToUpper(runes[0])
+		buf.WriteString(string(runes))
+	}
+	name := buf.String()
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
+
+func (field *genField) goType() string {
+	switch {
+	case field.child != nil && field.table:
+		return "[]" + field.child.name
+	case field.child != nil:
+		return field.child.name
+	case field.isMap:
+		return "map[string]string"
+	}
+	return goTypes[field.typ]
+}
+
+func (field *genField) structTag() string {
+	tag := field.tag
+	if field.child == nil && !field.isMap && field.typ == TypeSize {
+		tag += ",size"
+	}
+	return fmt.Sprintf("`ini:%s`", strconv.Quote(tag))
+}
+
+func (g *generator) emit() ([]byte, error) {
+	imports := map[string]bool{g.opt.ConfImport: true}
+	for _, s := range g.structs {
+		for _, field := range s.fields {
+			if pkg, ok := goImports[field.typ]; ok && field.child == nil && !field.isMap {
+				imports[pkg] = true
+			}
+		}
+	}
+	var pkgs []string
+	for pkg := range imports {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+
+	var buf bytes.Buffer
+	if g.opt.Source != "" {
+		fmt.Fprintf(&buf, "// Code generated by conf-gen from %s; DO NOT EDIT.\n\n", g.opt.Source)
+	} else {
+		buf.WriteString("// Code generated by conf-gen; DO NOT EDIT.\n\n")
+	}
+	fmt.Fprintf(&buf, "package %s\n\nimport (\n", g.opt.Package)
+	for _, pkg := range pkgs {
+		fmt.Fprintf(&buf, "\t%q\n", pkg)
+	}
+	buf.WriteString(")\n")
+
+	for _, s := range g.structs {
+		if s.path == "" {
+			fmt.Fprintf(&buf, "\n// %s 配置文件的全部内容\n", s.name)
+		} else {
+			fmt.Fprintf(&buf, "\n// %s 对应[%s]段落\n", s.name, s.path)
+		}
+		fmt.Fprintf(&buf, "type %s struct {\n", s.name)
+		for _, field := range s.fields {
+			fmt.Fprintf(&buf, "\t%s %s %s\n", field.name, field.goType(), field.structTag())
+		}
+		buf.WriteString("}\n")
+	}
+
+	name := g.opt.TypeName
+	fmt.Fprintf(&buf, `
+// Load%[1]s 解析path并绑定到%[1]s
+func Load%[1]s(path string) (*%[1]s, error) {
+	f, err := conf.NewParser(path)
+	if err != nil {
+		return nil, err
+	}
+	return Unmarshal%[1]s(f)
+}
+
+// Unmarshal%[1]s 将已经解析的文件绑定到%[1]s，可以在绑定之前设置环境变量和命令行参数覆盖
+func Unmarshal%[1]s(f *conf.FileReader) (*%[1]s, error) {
+	c := &%[1]s{}
+	if err := f.Sub("").Unmarshal("", c); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+`, name)
+	return gofmt.Source(buf.Bytes())
+}
+
+// previous 上一次生成的代码中段落对应的类型名以及"段落::键"对应的字段名
+type previous struct {
+	types  map[string]string
+	fields map[string]string
+}
+
+func parsePrevious(src []byte, top string) (previous, error) {
+	prev := previous{types: make(map[string]string), fields: make(map[string]string)}
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	if err != nil {
+		return prev, err
+	}
+	structs := make(map[string]*ast.StructType)
+	ast.Inspect(file, func(n ast.Node) bool {
+		if spec, ok := n.(*ast.TypeSpec); ok {
+			if st, ok := spec.Type.(*ast.StructType); ok {
+				structs[spec.Name.Name] = st
+			}
+		}
+		return true
+	})
+
+	visited := make(map[string]bool)
+	var walk func(name, path string)
+	walk = func(name, path string) {
+		st, ok := structs[name]
+		if !ok || visited[name] {
+			return
+		}
+		visited[name] = true
+		for _, field := range st.Fields.List {
+			if field.Tag == nil || len(field.Names) != 1 {
+				continue
+			}
+			raw, err := strconv.Unquote(field.Tag.Value)
+			if err != nil {
+				continue
+			}
+			tag := reflect.StructTag(raw).Get("ini")
+			if idx := strings.Index(tag, ","); idx >= 0 {
+				tag = tag[:idx]
+			}
+			if tag == "" || tag == "-" {
+				continue
+			}
+			prev.fields[path+"::"+tag] = field.Names[0].Name
+
+			typ := field.Type
+			if arr, ok := typ.(*ast.ArrayType); ok {
+				typ = arr.Elt
+			}
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			if ident, ok := typ.(*ast.Ident); ok {
+				if _, ok := structs[ident.Name]; ok {
+					child := childSection(path, tag)
+					prev.types[child] = ident.Name
+					walk(ident.Name, child)
+				}
+			}
+		}
+	}
+	walk(top, "")
+	return prev, nil
+}
@@ -0,0 +1,180 @@
+package conf
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestInferType(t *testing.T) {
+	expect := map[string]Type{
+		"80":                        TypeInt,
+		"-1":                        TypeInt,
+		"1.5":                       TypeFloat,
+		"true":                      TypeBool,
+		"y":                         TypeBool,
+		"30s":                       TypeDuration,
+		"2019-06-03T10:00:00+08:00": TypeTime,
+		"192.168.1.0/24":            TypeCIDR,
+		"127.0.0.1":                 TypeIP,
+		"https://www.51job.com":     TypeURL,
+		"10MB":                      TypeSize,
+		"[1,2,3]":                   TypeIntSlice,
+		"[1.2,2,3]":                 TypeFloatSlice,
+		"[h,cz,a]":                  TypeStringSlice,
+		"[]":                        TypeStringSlice,
+		"admin":                     TypeString,
+		"1.2.3":                     TypeString,
+		"":                          TypeString,
+	}
+	for v, typ := range expect {
+		if got := InferType(v); got != typ {
+			t.Errorf("%q: expect %s got %s", v, typ, got)
+		}
+	}
+}
+
+const genIni = `name = spider
+[user]
+username = admin
+needInit = true
+user_name = root
+timeout = 30s
+buffer = 10MB
+arrInt = [1,2,3]
+proxy_url = http://127.0.0.1:8080
+headers.Accept = text/html
+[user.db]
+port = 3306
+[[downloader]]
+weight = 1
+[[downloader]]
+weight = 0.5
+name =
+`
+
+func TestGenerate(t *testing.T) {
+	f, err := ParseString(genIni, INI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := NewSchema()
+	schema.Key("user::password", TypeString)
+	schema.Key("downloader::name", TypeString)
+	code, err := Generate(f, GenOptions{Source: "spider.ini", Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := string(code)
+	for _, line := range []string{
+		"// Code generated by conf-gen from spider.ini; DO NOT EDIT.",
+		"package config",
+		`"conf"`, `"net/url"`, `"time"`,
+		"Name       string       `ini:\"name\"`",
+		"User       User         `ini:\"user\"`",
+		"Downloader []Downloader `ini:\"downloader\"`",
+		"NeedInit bool              `ini:\"needInit\"`",
+		"UserName string            `ini:\"user_name\"`",
+		"Timeout  time.Duration     `ini:\"timeout\"`",
+		"Buffer   int64             `ini:\"buffer,size\"`",
+		"ArrInt   []int             `ini:\"arrInt\"`",
+		"ProxyURL *url.URL          `ini:\"proxy_url\"`",
+		"Headers  map[string]string `ini:\"headers\"`",
+		"Db       UserDb            `ini:\"db\"`",
+		"Password string            `ini:\"password\"`",
+		"Port int `ini:\"port\"`",
+		"Weight float64 `ini:\"weight\"`",
+		"func LoadConfig(path string) (*Config, error)",
+		"func UnmarshalConfig(f *conf.FileReader) (*Config, error)",
+	} {
+		if !strings.Contains(src, line) {
+			t.Errorf("missing %s in\n%s", line, src)
+		}
+	}
+
+	// 重新生成时沿用已有的名称，新增的键不会改变已有的字段名
+	prev := strings.Replace(src, "UserDb", "Database", -1)
+	f, _ = ParseString(strings.Replace(genIni, "[user]\n", "[user]\nuserName = x\n", 1), INI)
+	code, err = Generate(f, GenOptions{Source: "spider.ini", Schema: schema, Previous: []byte(prev)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	src = string(code)
+	for _, line := range []string{
+		"UserName  string            `ini:\"user_name\"`",
+		"UserName2 string            `ini:\"userName\"`",
+		"Db        Database          `ini:\"db\"`",
+		"type Database struct",
+	} {
+		if !strings.Contains(src, line) {
+			t.Errorf("missing %s in\n%s", line, src)
+		}
+	}
+}
+
+// confImporter 使用源码类型检查当前目录的conf包，其他包从GOROOT的源码导入
+type confImporter struct {
+	fset *token.FileSet
+	std  types.Importer
+	conf *types.Package
+}
+
+func (c *confImporter) Import(path string) (*types.Package, error) {
+	if path != "conf" {
+		return c.std.Import(path)
+	}
+	if c.conf != nil {
+		return c.conf, nil
+	}
+	pkgs, err := parser.ParseDir(c.fset, ".", func(info os.FileInfo) bool {
+		return !strings.HasSuffix(info.Name(), "_test.go")
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, file := range pkgs["conf"].Files {
+		files = append(files, file)
+	}
+	c.conf, err = (&types.Config{Importer: c.std}).Check("conf", c.fset, files, nil)
+	return c.conf, err
+}
+
+// 生成的代码与当前的conf包一起可以通过类型检查
+func TestGenerate_TypeCheck(t *testing.T) {
+	f, err := ParseString(genIni, INI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := NewSchema()
+	schema.Key("user::password", TypeString)
+	code, err := Generate(f, GenOptions{Source: "spider.ini", Schema: schema})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "config.go", code, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, code)
+	}
+	imp := &confImporter{fset: fset, std: importer.ForCompiler(fset, "source", nil)}
+	pkg, err := (&types.Config{Importer: imp}).Check("config", fset, []*ast.File{file}, nil)
+	if err != nil {
+		t.Fatalf("%v\n%s", err, code)
+	}
+	for _, obj := range []string{"Config", "User", "UserDb", "Downloader", "LoadConfig", "UnmarshalConfig"} {
+		if pkg.Scope().Lookup(obj) == nil {
+			t.Errorf("missing %s", obj)
+		}
+	}
+	load := pkg.Scope().Lookup("UnmarshalConfig").Type().(*types.Signature)
+	if param := load.Params().At(0).Type().String(); param != "*conf.FileReader" {
+		t.Errorf("got %s", param)
+	}
+}
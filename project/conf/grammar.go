@@ -0,0 +1,171 @@
+package conf
+
+import (
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// IgnoreCase 段落名和键名不区分大小写，需要在Parser之前调用，使用NewParser、Load等构造函数时通过WithIgnoreCase设置
+func (f *FileReader) IgnoreCase(ignore bool) {
+	if f.root != nil {
+		f.root.IgnoreCase(ignore)
+		return
+	}
+	f.ignoreCase = ignore
+}
+
+func (f *FileReader) name(s string) string {
+	if f.root != nil {
+		return f.root.name(s)
+	}
+	if f.ignoreCase {
+		return strings.ToLower(s)
+	}
+	return s
+}
+
+// stripComment 去掉行内注释，行内注释以空白字符后的#或;开始
+func stripComment(s string) string {
+	for i := 0; i < len(s); i++ {
+		if (s[i] == '#' || s[i] == ';') && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+			return strings.TrimSpace(s[:i])
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// readValue 解析'='之后的内容，支持引号、行内注释、以\结尾的续行以及<<TAG形式的多行值，
+// full为值所占的所有原始行，出错时col为出错位置相对raw的偏移
+func (f *FileReader) readValue(raw string) (v, full string, col int, err error) {
+	full = f.currentLine
+	if strings.HasPrefix(raw, "<<") {
+		if tag := stripComment(raw[2:]); isTag(tag) {
+			return f.readHeredoc(tag)
+		}
+	}
+	if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+		v, col, err = unquote(raw)
+		return v, full, col, err
+	}
+
+	v = stripComment(raw)
+	for strings.HasSuffix(v, "\\") {
+		if err := f.readLine(); err != nil && err != BOL {
+			return "", full, len(raw), errors.New("unexpected end of file after '\\'")
+		}
+		full += "\n" + f.currentLine
+		v = v[:len(v)-1] + stripComment(f.currentLine)
+	}
+	return v, full, 0, nil
+}
+
+// valueComment 返回值之后的行内注释，包括之前的空白，例如" ; http port"，多行值返回最后一行的注释
+func valueComment(raw, full string) string {
+	if strings.HasPrefix(raw, "<<") && isTag(stripComment(raw[2:])) {
+		return ""
+	}
+	if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+		q := raw[0]
+		for i := 1; i < len(raw); i++ {
+			if q == '"' && raw[i] == '\\' {
+				i++
+				continue
+			}
+			if raw[i] == q {
+				return inlineComment(raw[i+1:])
+			}
+		}
+		return ""
+	}
+	if idx := strings.LastIndex(full, "\n"); idx >= 0 {
+		raw = full[idx+1:]
+	}
+	return inlineComment(raw)
+}
+
+func inlineComment(s string) string {
+	for i := 0; i < len(s); i++ {
+		if (s[i] == '#' || s[i] == ';') && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t') {
+			start := i
+			for start > 0 && (s[start-1] == ' ' || s[start-1] == '\t') {
+				start--
+			}
+			if start == i {
+				return " " + s[i:]
+			}
+			return s[start:]
+		}
+	}
+	return ""
+}
+
+func isTag(tag string) bool {
+	if tag == "" {
+		return false
+	}
+	for _, c := range tag {
+		if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// readHeredoc 读取到只包含tag的行为止，中间的行原样保留
+func (f *FileReader) readHeredoc(tag string) (v, full string, col int, err error) {
+	full = f.currentLine
+	var lines []string
+	for {
+		if err := f.readLine(); err != nil && err != BOL {
+			if err == io.EOF {
+				return "", full, 0, errors.New("missing heredoc terminator " + tag)
+			}
+			return "", full, 0, err
+		}
+		full += "\n" + f.currentLine
+		if strings.TrimSpace(f.currentLine) == tag {
+			return strings.Join(lines, "\n"), full, 0, nil
+		}
+		lines = append(lines, f.currentLine)
+	}
+}
+
+// unquote 处理双引号（支持转义字符）和单引号（原样保留）包裹的值，引号之后只允许出现注释
+func unquote(raw string) (v string, col int, err error) {
+	q := raw[0]
+	for i := 1; i < len(raw); i++ {
+		if q == '"' && raw[i] == '\\' {
+			i++
+			continue
+		}
+		if raw[i] != q {
+			continue
+		}
+		if q == '\'' {
+			v = raw[1:i]
+		} else if v, err = strconv.Unquote(raw[:i+1]); err != nil {
+			return "", 0, errors.New("invalid escape sequence in quoted value")
+		}
+		rest := strings.TrimLeft(raw[i+1:], " \t")
+		if rest != "" && rest[0] != '#' && rest[0] != ';' {
+			return "", len(raw) - len(rest), errors.New("unexpected text after quoted value")
+		}
+		return v, 0, nil
+	}
+	return "", 0, errors.New("unterminated quoted value")
+}
+
+// quoteValue 回写时为包含特殊字符的值加上引号
+func quoteValue(v string) string {
+	if v == "" {
+		return v
+	}
+	if v != strings.TrimSpace(v) || strings.ContainsAny(v, "\"'\n\r") ||
+		strings.Contains(v, " #") || strings.Contains(v, " ;") || strings.Contains(v, "\t#") || strings.Contains(v, "\t;") ||
+		v[0] == '#' || v[0] == ';' || strings.HasPrefix(v, "<<") || strings.HasSuffix(v, "\\") {
+		return strconv.Quote(v)
+	}
+	return v
+}
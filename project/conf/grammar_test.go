@@ -0,0 +1,177 @@
+package conf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const grammarIni = `; spider config
+[Spider] # inline comment
+url = http://x/?a=b&c=d#top
+quoted = "  tab\tnew\nline \"q\" # not comment"
+single = 'C:\path\to ; not comment'  ; comment
+empty =
+inline = value ; comment
+  indented = yes
+selector = div.job, \
+           div.company
+css = <<EOF
+div.job_msg > p,
+div.company # raw
+EOF
+`
+
+func TestFileReader_Grammar(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"test.ini": grammarIni})
+	defer os.RemoveAll(dir)
+
+	f, err := Load(filepath.Join(dir, "test.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"Spider::url":      "http://x/?a=b&c=d#top",
+		"Spider::quoted":   "  tab\tnew\nline \"q\" # not comment",
+		"Spider::single":   `C:\path\to ; not comment`,
+		"Spider::empty":    "",
+		"Spider::inline":   "value",
+		"Spider::indented": "yes",
+		"Spider::selector": "div.job, div.company",
+		"Spider::css":      "div.job_msg > p,\ndiv.company # raw",
+	}
+	for k, v := range cases {
+		if res := f.Get(k).GetAsString(); res != v {
+			t.Errorf("%s: expect %q got %q", k, v, res)
+		}
+	}
+	if o, _ := f.Origin("Spider::css"); o.Line != 11 {
+		t.Errorf("expect line 11 got %d", o.Line)
+	}
+
+	var buf bytes.Buffer
+	f.WriteTo(&buf)
+	if buf.String() != grammarIni {
+		t.Errorf("round trip changed the file:\n%s", buf.String())
+	}
+
+	f.Set("Spider::inline", " a # b ")
+	f.Set("Spider::css", "p\np")
+	f.Save("")
+	back, err := Load(filepath.Join(dir, "test.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, v := range map[string]string{"Spider::inline": " a # b ", "Spider::css": "p\np"} {
+		if res := back.Get(k).GetAsString(); res != v {
+			t.Errorf("%s: expect %q got %q", k, v, res)
+		}
+	}
+}
+
+func TestFileReader_GrammarErrors(t *testing.T) {
+	_, err := parseTemp(t, "[a]\nk = \"abc\nq = 'x' y\nm = <<END\nx\n", Lenient)
+	errs, ok := err.(ParseErrors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("expect 3 errors got %v", err)
+	}
+	expect := []struct {
+		line   uint64
+		column int
+	}{{2, 5}, {3, 9}, {5, 5}}
+	for i, e := range errs {
+		if e.Line != expect[i].line || e.Column != expect[i].column {
+			t.Errorf("expect %d:%d got %s", expect[i].line, expect[i].column, e)
+		}
+	}
+}
+
+func TestFileReader_IgnoreCase(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"test.ini": "[User]\nUserName = admin\n"})
+	defer os.RemoveAll(dir)
+
+	f := &FileReader{}
+	f.IgnoreCase(true)
+	f.Open(filepath.Join(dir, "test.ini"))
+	if err := f.Parser(); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("USER::username").GetAsString(); v != "admin" {
+		t.Errorf("expect admin got %s", v)
+	}
+	f.Set("user::USERNAME", "root")
+	if v := f.Get("User::UserName").GetAsString(); v != "root" {
+		t.Errorf("expect root got %s", v)
+	}
+}
+
+func TestFileReader_IgnoreCaseIncludeReload(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spider.ini":       "[Spider]\nTimeout = 30\n%include proxy.ini\n",
+		"proxy.ini":        "[Proxy]\nHost = 127.0.0.1\n",
+		"spider.local.ini": "[SPIDER]\nWorker = 4\n",
+		"spider.json":      `{"Spider": {"Timeout": 10}}`,
+	})
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "spider.ini")
+
+	check := func(f *FileReader, key, expect string) {
+		t.Helper()
+		if v, ok := f.Lookup(key); !ok || v.GetAsString() != expect {
+			t.Errorf("%s: expect %s got %v", key, expect, v)
+		}
+	}
+
+	// 通过IgnoreCase设置时include的文件同样不区分大小写
+	f := &FileReader{}
+	f.IgnoreCase(true)
+	f.Open(name)
+	if err := f.Parser(); err != nil {
+		t.Fatal(err)
+	}
+	check(f, "spider::timeout", "30")
+	check(f, "PROXY::HOST", "127.0.0.1")
+
+	// 重新加载之后仍然不区分大小写
+	ioutil.WriteFile(name, []byte("[Spider]\nTimeout = 60\n%include proxy.ini\n"), 0600)
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	check(f, "spider::timeout", "60")
+	check(f, "proxy::host", "127.0.0.1")
+
+	f, err := NewParser(name, WithIgnoreCase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(f, "SPIDER::TIMEOUT", "60")
+	check(f, "proxy::host", "127.0.0.1")
+
+	f, err = LoadProfile(name, "", WithIgnoreCase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(f, "spider::timeout", "60")
+	check(f, "spider::worker", "4")
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	check(f, "spider::worker", "4")
+
+	f, err = NewParser(filepath.Join(dir, "spider.json"), WithIgnoreCase())
+	if err != nil {
+		t.Fatal(err)
+	}
+	check(f, "spider::timeout", "10")
+
+	// 没有设置时区分大小写
+	f, err = Load(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := f.Lookup("spider::timeout"); ok {
+		t.Error("expect case sensitive")
+	}
+}
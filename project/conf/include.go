@@ -0,0 +1,181 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Origin 值的来源，Line为0表示该值是运行时通过Set设置的
+type Origin struct {
+	File string
+	Line uint64
+}
+
+func (o Origin) String() string {
+	if o.Line == 0 {
+		return o.File
+	}
+	return fmt.Sprintf("%s:%d", o.File, o.Line)
+}
+
+// includePath 识别"%include path"，以及出现在第一个段落之前的"include = path"
+func includePath(text, space string) (string, bool) {
+	text = strings.TrimSpace(text)
+	if strings.HasPrefix(text, "%include") {
+		return stripComment(strings.TrimPrefix(text, "%include")), true
+	}
+	if space != "" {
+		return "", false
+	}
+	k, v, _, err := checkAndSplit(text)
+	if err != nil || k != "include" {
+		return "", false
+	}
+	return stripComment(v), true
+}
+
+func (f *FileReader) include(path string) error {
+	if path == "" {
+		return fmt.Errorf("empty include at line %d", f.lineNo)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(f.fileName), path)
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	stack := f.includes
+	if len(stack) == 0 {
+		self, err := filepath.Abs(f.fileName)
+		if err != nil {
+			return err
+		}
+		stack = []string{self}
+	}
+	for _, name := range stack {
+		if name == abs {
+			return fmt.Errorf("include cycle: %s -> %s", strings.Join(stack, " -> "), abs)
+		}
+	}
+
+	sub := f.spawn()
+	sub.includes = append(stack[:len(stack):len(stack)], abs)
+	err = sub.loadFile(path)
+	f.warnings = append(f.warnings, sub.warnings...)
+	if err != nil {
+		return err
+	}
+	f.included = append(append(f.included, path), sub.included...)
+	f.merge(sub)
+	return nil
+}
+
+// merge 将o中的值覆盖到f上，不修改f原有的段落结构
+func (f *FileReader) merge(o *FileReader) {
+	for space, ov := range o.data {
+		v := f.value(space)
+		for key, data := range ov.value {
+			if _, ok := v.value[key]; !ok {
+				v.cap++
+			}
+			v.value[key] = data
+			f.origin[space+"::"+key] = o.origin[space+"::"+key]
+		}
+		f.data[space] = *v
+	}
+}
+
+func (f *FileReader) files() []string {
+	return append([]string{f.fileName}, f.layers...)
+}
+
+// watchFiles 需要检查修改时间的文件，包括include进来的文件
+func (f *FileReader) watchFiles() []string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append(f.files(), f.included...)
+}
+
+// Origin 返回"section::key"最终生效的值来自哪个文件的哪一行
+func (f *FileReader) Origin(str string) (Origin, bool) {
+	space, key, err := splitKey(str)
+	if err != nil {
+		return Origin{}, false
+	}
+	if f.root != nil {
+		return f.root.Origin(f.scoped(space) + "::" + key)
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	space, key = f.name(space), f.name(key)
+	for {
+		if o, ok := f.origin[space+"::"+key]; ok {
+			return o, true
+		}
+		parent, ok := parentSection(space)
+		if !ok {
+			return Origin{}, false
+		}
+		space = parent
+	}
+}
+
+// Load 依次加载多个文件，根据扩展名选择Loader，后面文件中的值覆盖前面的值，回写时使用第一个文件的结构
+func Load(paths ...string) (*FileReader, error) {
+	return load(&FileReader{}, paths)
+}
+
+// LoadStrict 与Load相同，但使用严格模式解析所有文件
+func LoadStrict(paths ...string) (*FileReader, error) {
+	return load(&FileReader{mode: Strict}, paths)
+}
+
+// LoadWith 与Load相同，所有文件都使用opts解析
+func LoadWith(paths []string, opts ...Option) (*FileReader, error) {
+	return load(newFileReader(opts), paths)
+}
+
+// load 使用proto的解析选项加载文件
+func load(proto *FileReader, paths []string) (*FileReader, error) {
+	if len(paths) == 0 {
+		return nil, errors.New("no file to load")
+	}
+	f := proto.spawn()
+	if err := f.loadFile(paths[0]); err != nil {
+		return nil, err
+	}
+	for _, path := range paths[1:] {
+		layer := proto.spawn()
+		err := layer.loadFile(path)
+		f.warnings = append(f.warnings, layer.warnings...)
+		if err != nil {
+			return nil, err
+		}
+		f.included = append(f.included, layer.included...)
+		f.merge(layer)
+	}
+	f.layers = append([]string(nil), paths[1:]...)
+	return f, nil
+}
+
+// LoadProfile 按照 基础文件 -> 环境文件 -> 本地文件 的顺序加载，例如spider.ini、spider.prod.ini、spider.local.ini，
+// 只有基础文件是必须存在的
+func LoadProfile(path, profile string, opts ...Option) (*FileReader, error) {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	paths := []string{path}
+	candidates := []string{base + ".local" + ext}
+	if profile != "" {
+		candidates = []string{base + "." + profile + ext, base + ".local" + ext}
+	}
+	for _, name := range candidates {
+		if _, err := os.Stat(name); err == nil {
+			paths = append(paths, name)
+		}
+	}
+	return LoadWith(paths, opts...)
+}
@@ -0,0 +1,100 @@
+package conf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestFileReader_Include(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spider.ini": "include = common.ini\n\n[spider]\nworker = 4\n%include extra.ini\n",
+		"common.ini": "[spider]\nworker = 1\ntimeout = 30\n",
+		"extra.ini":  "[proxy]\nhost = 127.0.0.1\n",
+	})
+	defer os.RemoveAll(dir)
+
+	f, err := Load(filepath.Join(dir, "spider.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("spider::worker").GetAsString(); v != "4" {
+		t.Errorf("expect 4 got %s", v)
+	}
+	if v := f.Get("spider::timeout").GetAsString(); v != "30" {
+		t.Errorf("expect 30 got %s", v)
+	}
+	if v := f.Get("proxy::host").GetAsString(); v != "127.0.0.1" {
+		t.Errorf("got %s", v)
+	}
+	if o, _ := f.Origin("spider::timeout"); o.File != filepath.Join(dir, "common.ini") || o.Line != 3 {
+		t.Errorf("got %s", o)
+	}
+
+	var buf bytes.Buffer
+	f.WriteTo(&buf)
+	if buf.String() != "include = common.ini\n\n[spider]\nworker = 4\n%include extra.ini\n" {
+		t.Errorf("included values should not be written back:\n%s", buf.String())
+	}
+}
+
+func TestFileReader_IncludeCycle(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a.ini": "%include b.ini\n",
+		"b.ini": "%include a.ini\n",
+	})
+	defer os.RemoveAll(dir)
+
+	if _, err := Load(filepath.Join(dir, "a.ini")); err == nil {
+		t.Error("include cycle should fail")
+	} else {
+		t.Log(err)
+	}
+}
+
+func TestLoadProfile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spider.ini":       "[spider]\nworker = 1\ntimeout = 30\nlimit = 10\n",
+		"spider.prod.ini":  "[spider]\nworker = 8\nlimit = 100\n",
+		"spider.local.ini": "[spider]\nlimit = 1\n",
+	})
+	defer os.RemoveAll(dir)
+
+	f, err := LoadProfile(filepath.Join(dir, "spider.ini"), "prod")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := map[string]string{
+		"spider::worker":  "spider.prod.ini:2",
+		"spider::timeout": "spider.ini:3",
+		"spider::limit":   "spider.local.ini:2",
+	}
+	for k, v := range expect {
+		o, ok := f.Origin(k)
+		if !ok || filepath.Base(o.String()) != v {
+			t.Errorf("%s: expect %s got %s", k, v, o)
+		}
+	}
+	if v := f.Get("spider::worker").GetAsString(); v != "8" {
+		t.Errorf("expect 8 got %s", v)
+	}
+
+	if _, err := LoadProfile(filepath.Join(dir, "spider.ini"), "dev"); err != nil {
+		t.Error("missing profile file should be ignored")
+	}
+}
@@ -0,0 +1,229 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvOverride 开启环境变量覆盖，section::key对应的环境变量名为 prefix + SECTION_KEY，
+// 其中非字母数字的字符替换为下划线，例如user::username对应USER_USERNAME
+func (f *FileReader) EnvOverride(prefix string) {
+	if f.root != nil {
+		f.root.EnvOverride(prefix)
+		return
+	}
+	f.env = true
+	f.envPrefix = prefix
+}
+
+func envName(prefix, section, key string) string {
+	name := []byte(strings.ToUpper(prefix + section + "_" + key))
+	for i, c := range name {
+		if (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' {
+			name[i] = '_'
+		}
+	}
+	return string(name)
+}
+
+// lookup 查找键对应的值，依次应用环境变量覆盖和变量替换
+func (f *FileReader) lookup(section, key string) (*conv, bool, error) {
+	if f.root != nil {
+		return f.root.lookup(f.scoped(section), key)
+	}
+	return f.resolve(section, key, nil)
+}
+
+// raw 查找未经变量替换的值，当前段落中不存在时依次到上一级段落中查找，最后使用Schema中的默认值
+func (f *FileReader) raw(section, key string) (interface{}, bool) {
+	data, _, ok := f.find(section, key)
+	return data, ok
+}
+
+// find 与raw相同，同时返回值的来源
+func (f *FileReader) find(section, key string) (interface{}, string, bool) {
+	section, key = f.name(section), f.name(key)
+	for space := section; ; {
+		if data, src, ok := f.own(space, key); ok {
+			return data, src, true
+		}
+		parent, ok := parentSection(space)
+		if !ok {
+			break
+		}
+		space = parent
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	data, ok := f.defaults[section+"::"+key]
+	return data, "default", ok
+}
+
+func (f *FileReader) setDefault(section, key, value string) {
+	if f.root != nil {
+		f.root.setDefault(f.scoped(section), key, value)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.defaults == nil {
+		f.defaults = make(map[string]string)
+	}
+	f.defaults[f.name(section)+"::"+f.name(key)] = value
+}
+
+// own 只在当前段落中查找，优先级为 命令行参数 > 环境变量 > 文件
+func (f *FileReader) own(section, key string) (interface{}, string, bool) {
+	name := section + "::" + key
+	f.mu.RLock()
+	v, ok := f.flags[name]
+	flagName := f.flagNames[name]
+	f.mu.RUnlock()
+	if ok {
+		return v, "flag --" + flagName, true
+	}
+	if f.env {
+		env := envName(f.envPrefix, section, key)
+		if v, ok := os.LookupEnv(env); ok {
+			return v, "env " + env, true
+		}
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	value, ok := f.data[section]
+	if !ok {
+		return nil, "", false
+	}
+	d, ok := value.Get(key)
+	if !ok {
+		return nil, "", false
+	}
+	origin, has := f.origin[name]
+	if !has {
+		origin = Origin{File: f.fileName}
+	}
+	return d.data, origin.String(), true
+}
+
+func (f *FileReader) resolve(section, key string, stack []string) (*conv, bool, error) {
+	data, ok := f.raw(section, key)
+	if !ok {
+		return &conv{}, false, nil
+	}
+	str, isString := data.(string)
+	if isString && IsEncrypted(str) {
+		plain, err := f.decrypt(str)
+		if err != nil {
+			return nil, true, fmt.Errorf("%s::%s: %v", section, key, err)
+		}
+		return &conv{data: plain}, true, nil
+	}
+	if !isString || !strings.Contains(str, "${") {
+		return &conv{data: data}, true, nil
+	}
+
+	name := f.name(section) + "::" + f.name(key)
+	for i, s := range stack {
+		if s == name {
+			return nil, true, fmt.Errorf("cycle reference: %s -> %s", strings.Join(stack[i:], " -> "), name)
+		}
+	}
+	res, err := f.expand(str, append(stack, name))
+	if err != nil {
+		return nil, true, err
+	}
+	return &conv{data: res}, true, nil
+}
+
+// Expand 替换字符串中的${section::key}、${ENV}以及${ENV:-default}，$${ 表示字面量${
+func (f *FileReader) Expand(str string) (string, error) {
+	if f.root != nil {
+		return f.root.Expand(str)
+	}
+	return f.expand(str, nil)
+}
+
+func (f *FileReader) expand(str string, stack []string) (string, error) {
+	var buf strings.Builder
+	for {
+		idx := strings.Index(str, "${")
+		if idx < 0 {
+			buf.WriteString(str)
+			return buf.String(), nil
+		}
+		if idx > 0 && str[idx-1] == '$' {
+			buf.WriteString(str[:idx-1] + "${")
+			str = str[idx+2:]
+			continue
+		}
+		buf.WriteString(str[:idx])
+
+		end := closeBrace(str[idx+2:])
+		if end < 0 {
+			return "", fmt.Errorf("unclosed ${ in %q", str)
+		}
+		ref := str[idx+2 : idx+2+end]
+		str = str[idx+3+end:]
+
+		res, err := f.reference(ref, stack)
+		if err != nil {
+			return "", err
+		}
+		buf.WriteString(res)
+	}
+}
+
+// closeBrace 返回与开头的${匹配的}的位置，支持嵌套
+func closeBrace(str string) int {
+	depth := 0
+	for i := 0; i < len(str); i++ {
+		switch {
+		case str[i] == '$' && i+1 < len(str) && str[i+1] == '{':
+			depth++
+			i++
+		case str[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+func (f *FileReader) reference(ref string, stack []string) (string, error) {
+	name, def, hasDef := ref, "", false
+	if idx := strings.Index(ref, ":-"); idx >= 0 {
+		name, def, hasDef = ref[:idx], ref[idx+2:], true
+	}
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", errors.New("empty reference ${}")
+	}
+
+	var res string
+	if strings.Contains(name, "::") {
+		space, key, err := splitKey(name)
+		if err != nil {
+			return "", err
+		}
+		c, ok, err := f.resolve(space, key, stack)
+		if err != nil {
+			return "", err
+		}
+		if !ok && !hasDef {
+			return "", fmt.Errorf("no such key %s", name)
+		}
+		if ok {
+			res = fmt.Sprint(c.data)
+		}
+	} else {
+		res = os.Getenv(name)
+	}
+	if res == "" && hasDef {
+		return f.expand(def, stack)
+	}
+	return res, nil
+}
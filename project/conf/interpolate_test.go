@@ -0,0 +1,70 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const interpolateIni = `[user]
+username = admin
+home = ${CONF_TEST_HOME}/spider
+port = ${CONF_TEST_PORT:-8080}
+server = http://${user::host:-localhost}:${user::port}/
+escape = $${user::username}
+
+[cycle]
+a = ${cycle::b}
+b = ${cycle::c}
+c = ${cycle::a}
+missing = ${cycle::none}
+`
+
+func TestFileReader_Interpolate(t *testing.T) {
+	f, name := openTemp(t, interpolateIni)
+	defer os.RemoveAll(filepath.Dir(name))
+	os.Setenv("CONF_TEST_HOME", "/home/spider")
+	defer os.Unsetenv("CONF_TEST_HOME")
+
+	cases := map[string]string{
+		"user::home":   "/home/spider/spider",
+		"user::port":   "8080",
+		"user::server": "http://localhost:8080/",
+		"user::escape": "${user::username}",
+	}
+	for k, v := range cases {
+		if res := f.Get(k).GetAsString(); res != v {
+			t.Errorf("%s: expect %s got %s", k, v, res)
+		}
+	}
+
+	if _, _, err := f.lookup("cycle", "a"); err == nil {
+		t.Error("cycle reference should fail")
+	} else {
+		t.Log(err)
+	}
+	if _, _, err := f.lookup("cycle", "missing"); err == nil {
+		t.Error("missing reference should fail")
+	}
+}
+
+func TestFileReader_EnvOverride(t *testing.T) {
+	f, name := openTemp(t, interpolateIni)
+	defer os.RemoveAll(filepath.Dir(name))
+	os.Setenv("USER_USERNAME", "root")
+	os.Setenv("SPIDER_USER_PORT", "9090")
+	defer os.Unsetenv("USER_USERNAME")
+	defer os.Unsetenv("SPIDER_USER_PORT")
+
+	if res := f.Get("user::username").GetAsString(); res != "admin" {
+		t.Errorf("override should be disabled by default, got %s", res)
+	}
+	f.EnvOverride("")
+	if res := f.Get("user::username").GetAsString(); res != "root" {
+		t.Errorf("expect root got %s", res)
+	}
+	f.EnvOverride("SPIDER_")
+	if res := f.Get("user::server").GetAsString(); res != "http://localhost:9090/" {
+		t.Errorf("got %s", res)
+	}
+}
@@ -0,0 +1,275 @@
+package conf
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Loader 将不同格式的内容解析到FileReader中，解析后可以使用相同的Get、Unmarshal等方法访问
+type Loader interface {
+	Load(f *FileReader, r io.Reader) error
+}
+
+type iniLoader struct{}
+
+type jsonLoader struct{}
+
+type dotEnvLoader struct{}
+
+var (
+	INI    Loader = iniLoader{}
+	JSON   Loader = jsonLoader{}
+	DotEnv Loader = dotEnvLoader{}
+)
+
+var (
+	loaderMu sync.RWMutex
+	loaders  = map[string]Loader{
+		".ini":  INI,
+		".conf": INI,
+		".cfg":  INI,
+		".json": JSON,
+		".env":  DotEnv,
+	}
+)
+
+// RegisterLoader 为文件扩展名注册Loader，例如RegisterLoader(".yaml", yamlLoader)
+func RegisterLoader(ext string, loader Loader) {
+	loaderMu.Lock()
+	defer loaderMu.Unlock()
+	loaders[strings.ToLower(ext)] = loader
+}
+
+// loaderFor 根据扩展名选择Loader，未注册的扩展名按INI处理
+func loaderFor(path string) Loader {
+	loaderMu.RLock()
+	defer loaderMu.RUnlock()
+	if l, ok := loaders[strings.ToLower(filepath.Ext(path))]; ok {
+		return l
+	}
+	return INI
+}
+
+// Option NewParser、NewReader、LoadWith等构造函数的选项，在解析之前生效
+type Option func(*FileReader)
+
+// WithIgnoreCase 段落名和键名不区分大小写
+func WithIgnoreCase() Option {
+	return func(f *FileReader) {
+		f.ignoreCase = true
+	}
+}
+
+// WithMode 使用指定的模式解析
+func WithMode(mode Mode) Option {
+	return func(f *FileReader) {
+		f.mode = mode
+	}
+}
+
+func newFileReader(opts []Option) *FileReader {
+	f := &FileReader{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// spawn 返回使用相同解析选项的空FileReader，用于解析include的文件、覆盖的文件以及重新加载
+func (f *FileReader) spawn() *FileReader {
+	return &FileReader{mode: f.mode, ignoreCase: f.ignoreCase}
+}
+
+// NewParser 打开并解析文件，根据扩展名选择Loader
+func NewParser(path string, opts ...Option) (*FileReader, error) {
+	f := newFileReader(opts)
+	if err := f.loadFile(path); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// NewReader 使用指定的Loader解析r中的内容
+func NewReader(r io.Reader, loader Loader, opts ...Option) (*FileReader, error) {
+	f := newFileReader(opts)
+	f.reset()
+	f.loader = loader
+	if err := loader.Load(f, r); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ParseString 使用指定的Loader解析字符串
+func ParseString(s string, loader Loader, opts ...Option) (*FileReader, error) {
+	return NewReader(strings.NewReader(s), loader, opts...)
+}
+
+func (f *FileReader) loadFile(path string) error {
+	file, err := os.Open(path)
+	f.reset()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	f.fileName = path
+	f.loader = loaderFor(path)
+	return f.loader.Load(f, file)
+}
+
+func (iniLoader) Load(f *FileReader, r io.Reader) error {
+	f.reader = bufio.NewReader(r)
+	f.lineNo = 0
+	return f.Parser()
+}
+
+// Load 顶层的对象对应段落，嵌套的对象对应子段落，元素都是对象的数组对应[[name]]，
+// 顶层的其他值放在名字为空的段落中，其他数组转换为"[a,b,c]"
+func (jsonLoader) Load(f *FileReader, r io.Reader) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+	var data map[string]interface{}
+	if err := dec.Decode(&data); err != nil {
+		return fmt.Errorf("%s: %v", f.fileName, err)
+	}
+	return f.setJSON("", data)
+}
+
+func (f *FileReader) setJSON(section string, data map[string]interface{}) error {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var children, tables []string
+	for _, k := range keys {
+		if _, ok := data[k].(map[string]interface{}); ok {
+			children = append(children, k)
+			continue
+		}
+		if isObjectArray(data[k]) {
+			tables = append(tables, k)
+			continue
+		}
+		v, err := jsonValue(data[k])
+		if err != nil {
+			return fmt.Errorf("%s::%s: %v", section, k, err)
+		}
+		if err := f.Set(section+"::"+k, v); err != nil {
+			return err
+		}
+	}
+	for _, k := range children {
+		name := childSection(section, k)
+		if err := f.AddSection(name); err != nil {
+			return err
+		}
+		if err := f.setJSON(name, data[k].(map[string]interface{})); err != nil {
+			return err
+		}
+	}
+	for _, k := range tables {
+		name := childSection(section, k)
+		for _, item := range data[k].([]interface{}) {
+			idx, err := f.AppendTable(name)
+			if err != nil {
+				return err
+			}
+			if err := f.setJSON(tableName(name, idx), item.(map[string]interface{})); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isObjectArray 元素全部是对象的数组对应重复出现的段落
+func isObjectArray(v interface{}) bool {
+	arr, ok := v.([]interface{})
+	if !ok || len(arr) == 0 {
+		return false
+	}
+	for _, item := range arr {
+		if _, ok := item.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func jsonValue(v interface{}) (string, error) {
+	switch d := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return d, nil
+	case json.Number:
+		return d.String(), nil
+	case bool:
+		return fmt.Sprint(d), nil
+	case []interface{}:
+		arr := make([]string, len(d))
+		for i, item := range d {
+			s, err := jsonValue(item)
+			if err != nil {
+				return "", err
+			}
+			arr[i] = s
+		}
+		return "[" + strings.Join(arr, ",") + "]", nil
+	}
+	return "", errors.New("nested object in array is not supported")
+}
+
+// Load 解析KEY=VALUE形式的.env文件，所有的键都放在名字为空的段落中，使用"::KEY"访问，
+// 支持export前缀、注释以及引号
+func (dotEnvLoader) Load(f *FileReader, r io.Reader) error {
+	var errs ParseErrors
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		f.lineNo++
+		f.currentLine = scanner.Text()
+		text := strings.TrimSpace(f.currentLine)
+		if text == "" || text[0] == '#' {
+			f.appendLine(f.currentLine)
+			continue
+		}
+		text = strings.TrimSpace(strings.TrimPrefix(text, "export "))
+		k, raw, col, err := checkAndSplit(text)
+		if err != nil {
+			errs = append(errs, f.newError(col, err.Error()))
+			f.appendLine(f.currentLine)
+			continue
+		}
+		v := stripComment(raw)
+		if raw != "" && (raw[0] == '"' || raw[0] == '\'') {
+			if v, _, err = unquote(raw); err != nil {
+				errs = append(errs, f.newError(strings.Index(f.currentLine, raw)+1, err.Error()))
+				f.appendLine(f.currentLine)
+				continue
+			}
+		}
+		v0 := f.value("")
+		v0.Put(k, v)
+		f.data[""] = *v0
+		f.origin["::"+k] = Origin{File: f.fileName, Line: f.lineNo}
+		f.sections[0].lines = append(f.sections[0].lines, &line{kind: keyLine, text: f.currentLine, key: k, value: v})
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
@@ -0,0 +1,97 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestNewParser(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spider.ini":  "[user]\nusername = admin\nport = 80\n",
+		"spider.json": `{"name": "spider", "user": {"port": 8080, "needInit": true, "arrInt": [1, 2, 3], "proxy": {"host": null}}}`,
+		".env":        "# env\nexport PORT=9090\nNAME=\"spider # 1\"\nHOME='/home/spider' # home\n",
+	})
+	defer os.RemoveAll(dir)
+
+	f, err := NewParser(filepath.Join(dir, "spider.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("user::port").GetAsInt(); v != 80 {
+		t.Errorf("expect 80 got %d", v)
+	}
+
+	f, err = NewParser(filepath.Join(dir, "spider.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("::name").GetAsString(); v != "spider" {
+		t.Errorf("expect spider got %s", v)
+	}
+	if v := f.Get("user.proxy::port").GetAsInt(); v != 8080 {
+		t.Errorf("expect 8080 got %d", v)
+	}
+	if v, _ := f.Get("user::needInit").GetAsBool(); !v {
+		t.Error("expect true")
+	}
+	if arr, _ := f.Get("user::arrInt").GetAsIntSlice(); len(arr) != 3 {
+		t.Errorf("got %v", arr)
+	}
+
+	f, err = NewParser(filepath.Join(dir, ".env"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{"::PORT": "9090", "::NAME": "spider # 1", "::HOME": "/home/spider"}
+	for k, v := range cases {
+		if res := f.Get(k).GetAsString(); res != v {
+			t.Errorf("%s: expect %s got %s", k, v, res)
+		}
+	}
+
+	layered, err := Load(filepath.Join(dir, "spider.ini"), filepath.Join(dir, "spider.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := layered.Get("user::port").GetAsInt(); v != 8080 {
+		t.Errorf("expect 8080 got %d", v)
+	}
+	if v := layered.Get("user::username").GetAsString(); v != "admin" {
+		t.Errorf("expect admin got %s", v)
+	}
+}
+
+func TestParseString(t *testing.T) {
+	f, err := ParseString("[user]\nusername = admin\n", INI)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("user::username").GetAsString(); v != "admin" {
+		t.Errorf("expect admin got %s", v)
+	}
+
+	f, err = NewReader(strings.NewReader(`{"user": {"username": "root"}}`), JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg struct {
+		User struct {
+			Username string `ini:"username"`
+		} `ini:"user"`
+	}
+	if err := f.Unmarshal("", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.User.Username != "root" {
+		t.Errorf("expect root got %s", cfg.User.Username)
+	}
+
+	if _, err := ParseString(`{"user": [{"a": 1}, 2]}`, JSON); err == nil {
+		t.Error("array mixed with objects should fail")
+	}
+	if _, err := ParseString("PORT 80\n", DotEnv); err == nil {
+		t.Error("line without '=' should fail")
+	}
+}
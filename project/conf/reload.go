@@ -0,0 +1,236 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Event 配置变化事件，Old为nil表示新增的键，New为nil表示被删除的键
+type Event struct {
+	Section string
+	Key     string
+	Old     *conv
+	New     *conv
+}
+
+type subscriber struct {
+	id  uint64
+	key string
+	fn  func(Event)
+}
+
+type watcher struct {
+	mu          sync.Mutex
+	subscribers []subscriber
+	nextID      uint64
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+func (f *FileReader) getWatcher() *watcher {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.watcher == nil {
+		f.watcher = &watcher{}
+	}
+	return f.watcher
+}
+
+// Subscribe 订阅配置变化，key为"section::key"时只接收该键的变化，为"section"时接收整个段落的变化，
+// 为空时接收所有变化，返回的函数用于取消订阅
+func (f *FileReader) Subscribe(key string, fn func(Event)) (cancel func()) {
+	if f.root != nil {
+		// 视图只接收根段落以及子段落的变化，Event中的段落名相对于根段落
+		return f.root.Subscribe("", func(e Event) {
+			section, ok := f.relative(e.Section)
+			if !ok {
+				return
+			}
+			e.Section = section
+			if key == "" || key == section || key == section+"::"+e.Key {
+				fn(e)
+			}
+		})
+	}
+	w := f.getWatcher()
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.nextID++
+	id := w.nextID
+	w.subscribers = append(w.subscribers, subscriber{id: id, key: key, fn: fn})
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		for i, s := range w.subscribers {
+			if s.id == id {
+				w.subscribers = append(w.subscribers[:i], w.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Reload 重新读取并解析文件，解析失败时保留当前的配置
+func (f *FileReader) Reload() error {
+	if f.root != nil {
+		return f.root.Reload()
+	}
+	if f.fileName == "" {
+		return errors.New("file not read")
+	}
+	fresh, err := load(f, f.files())
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	old := f.data
+	f.data, f.sections, f.origin = fresh.data, fresh.sections, fresh.origin
+	f.included = fresh.included
+	f.warnings = fresh.warnings
+	f.mu.Unlock()
+
+	f.notify(diff(old, fresh.data))
+	return nil
+}
+
+func diff(old, new map[string]Value) []Event {
+	var events []Event
+	for space, ov := range old {
+		nv, ok := new[space]
+		for key, o := range ov.value {
+			if !ok {
+				events = append(events, Event{Section: space, Key: key, Old: &conv{data: o}})
+				continue
+			}
+			n, has := nv.value[key]
+			if !has {
+				events = append(events, Event{Section: space, Key: key, Old: &conv{data: o}})
+			} else if n != o {
+				events = append(events, Event{Section: space, Key: key, Old: &conv{data: o}, New: &conv{data: n}})
+			}
+		}
+	}
+	for space, nv := range new {
+		ov := old[space]
+		for key, n := range nv.value {
+			if _, ok := ov.value[key]; !ok {
+				events = append(events, Event{Section: space, Key: key, New: &conv{data: n}})
+			}
+		}
+	}
+	return events
+}
+
+func (f *FileReader) notify(events []Event) {
+	f.mu.RLock()
+	w := f.watcher
+	f.mu.RUnlock()
+	if len(events) == 0 || w == nil {
+		return
+	}
+	w.mu.Lock()
+	subscribers := make([]subscriber, len(w.subscribers))
+	copy(subscribers, w.subscribers)
+	w.mu.Unlock()
+
+	for _, e := range events {
+		for _, s := range subscribers {
+			if s.key == "" || s.key == e.Section || s.key == e.Section+"::"+e.Key {
+				s.fn(e)
+			}
+		}
+	}
+}
+
+// stat 所有文件的修改时间和大小，任意一个发生变化都需要重新加载
+func stat(files []string) (string, error) {
+	var sign strings.Builder
+	for _, name := range files {
+		info, err := os.Stat(name)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&sign, "%s:%d:%d;", name, info.ModTime().UnixNano(), info.Size())
+	}
+	return sign.String(), nil
+}
+
+// Watch 按interval轮询文件以及include进来的文件的修改时间和大小，发生变化时重新加载，onError接收重新加载时的错误，可以为nil
+func (f *FileReader) Watch(interval time.Duration, onError func(error)) error {
+	if f.root != nil {
+		return f.root.Watch(interval, onError)
+	}
+	if f.fileName == "" {
+		return errors.New("file not read")
+	}
+	if interval <= 0 {
+		interval = time.Second
+	}
+	last, err := stat(f.watchFiles())
+	if err != nil {
+		return err
+	}
+
+	w := f.getWatcher()
+	w.mu.Lock()
+	if w.stop != nil {
+		w.mu.Unlock()
+		return errors.New("already watching")
+	}
+	stop, done := make(chan struct{}), make(chan struct{})
+	w.stop, w.done = stop, done
+	w.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+			// 重新加载之后include的文件可能发生变化，每次都重新获取
+			sign, err := stat(f.watchFiles())
+			if err != nil {
+				if onError != nil {
+					onError(err)
+				}
+				continue
+			}
+			if sign == last {
+				continue
+			}
+			last = sign
+			if err := f.Reload(); err != nil && onError != nil {
+				onError(err)
+			}
+		}
+	}()
+	return nil
+}
+
+// StopWatch 停止轮询并等待后台的goroutine退出
+func (f *FileReader) StopWatch() {
+	if f.root != nil {
+		f.root.StopWatch()
+		return
+	}
+	w := f.getWatcher()
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
@@ -0,0 +1,100 @@
+package conf
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestFileReader_Reload(t *testing.T) {
+	f, name := openTemp(t, "[spider]\nlimit = 10\nworker = 2\n")
+	defer os.RemoveAll(filepath.Dir(name))
+
+	var all, limit []Event
+	f.Subscribe("", func(e Event) { all = append(all, e) })
+	cancel := f.Subscribe("spider::limit", func(e Event) { limit = append(limit, e) })
+
+	ioutil.WriteFile(name, []byte("[spider]\nlimit = 20\ndelay = 1s\n"), 0600)
+	if err := f.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Errorf("expect 3 events got %d", len(all))
+	}
+	if len(limit) != 1 || limit[0].Old.GetAsString() != "10" || limit[0].New.GetAsString() != "20" {
+		t.Errorf("got %+v", limit)
+	}
+	if f.Get("spider::delay").GetAsString() != "1s" {
+		t.Error("reload not applied")
+	}
+
+	cancel()
+	ioutil.WriteFile(name, []byte("[spider]\nlimit = 30\n"), 0600)
+	f.Reload()
+	if len(limit) != 1 {
+		t.Error("cancelled subscriber should not be notified")
+	}
+}
+
+func TestFileReader_Watch(t *testing.T) {
+	f, name := openTemp(t, "[spider]\nlimit = 10\n")
+	defer os.RemoveAll(filepath.Dir(name))
+
+	events := make(chan Event, 1)
+	f.Subscribe("spider", func(e Event) { events <- e })
+	if err := f.Watch(10*time.Millisecond, func(err error) { t.Log(err) }); err != nil {
+		t.Fatal(err)
+	}
+	defer f.StopWatch()
+	if err := f.Watch(10*time.Millisecond, nil); err == nil {
+		t.Error("watch twice should fail")
+	}
+
+	ioutil.WriteFile(name, []byte("[spider]\nlimit = 100\n"), 0600)
+	select {
+	case e := <-events:
+		if e.New.GetAsString() != "100" {
+			t.Errorf("got %+v", e)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("change not detected")
+	}
+}
+
+// include进来的文件发生变化时也会重新加载
+func TestFileReader_WatchInclude(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spider.ini": "%include limit.ini\n\n[spider]\nworker = 2\n",
+		"limit.ini":  "%include delay.ini\n\n[spider]\nlimit = 10\n",
+		"delay.ini":  "[spider]\ndelay = 1s\n",
+	})
+	defer os.RemoveAll(dir)
+	f, err := NewParser(filepath.Join(dir, "spider.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := make(chan Event, 1)
+	f.Subscribe("spider", func(e Event) { events <- e })
+	if err := f.Watch(10*time.Millisecond, func(err error) { t.Log(err) }); err != nil {
+		t.Fatal(err)
+	}
+	defer f.StopWatch()
+
+	for _, c := range []struct{ file, content, key, want string }{
+		{"limit.ini", "%include delay.ini\n\n[spider]\nlimit = 100\n", "limit", "100"},
+		{"delay.ini", "[spider]\ndelay = 10s\n", "delay", "10s"},
+	} {
+		ioutil.WriteFile(filepath.Join(dir, c.file), []byte(c.content), 0600)
+		select {
+		case e := <-events:
+			if e.Key != c.key || e.New.GetAsString() != c.want {
+				t.Errorf("%s: got %+v", c.file, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: change not detected", c.file)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package conf
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Type uint8
+
+const (
+	TypeString Type = iota
+	TypeInt
+	TypeFloat
+	TypeBool
+	TypeIntSlice
+	TypeFloatSlice
+	TypeStringSlice
+	TypeDuration
+	TypeSize
+	TypeTime
+	TypeURL
+	TypeIP
+	TypeCIDR
+)
+
+var typeNames = map[Type]string{
+	TypeString:      "string",
+	TypeInt:         "int",
+	TypeFloat:       "float",
+	TypeBool:        "bool",
+	TypeIntSlice:    "[]int",
+	TypeFloatSlice:  "[]float",
+	TypeStringSlice: "[]string",
+	TypeDuration:    "duration",
+	TypeSize:        "size",
+	TypeTime:        "time",
+	TypeURL:         "url",
+	TypeIP:          "ip",
+	TypeCIDR:        "cidr",
+}
+
+func (t Type) String() string {
+	if name, ok := typeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("Type(%d)", uint8(t))
+}
+
+// Rule 单个键的约束，通过Schema.Key创建
+type Rule struct {
+	key      string
+	typ      Type
+	required bool
+	def      *string
+	min, max *float64
+	pattern  *regexp.Regexp
+	enum     []string
+}
+
+func (r *Rule) Required() *Rule {
+	r.required = true
+	return r
+}
+
+// Default 键不存在时使用的值，Validate之后对Get可见，但不会被写回文件
+func (r *Rule) Default(v string) *Rule {
+	r.def = &v
+	return r
+}
+
+// Min 数字类型比较值的大小，字符串和数组类型比较长度，TypeDuration按纳秒比较，TypeSize按字节比较
+func (r *Rule) Min(v float64) *Rule {
+	r.min = &v
+	return r
+}
+
+func (r *Rule) Max(v float64) *Rule {
+	r.max = &v
+	return r
+}
+
+func (r *Rule) Range(min, max float64) *Rule {
+	return r.Min(min).Max(max)
+}
+
+// Match 值必须匹配正则表达式，数组类型要求每个元素都匹配
+func (r *Rule) Match(pattern string) *Rule {
+	r.pattern = regexp.MustCompile(pattern)
+	return r
+}
+
+// Enum 值必须是其中之一，数组类型要求每个元素都是其中之一
+func (r *Rule) Enum(values ...string) *Rule {
+	r.enum = values
+	return r
+}
+
+type Schema struct {
+	rules []*Rule
+}
+
+func NewSchema() *Schema {
+	return &Schema{}
+}
+
+// Key 声明"section::key"的类型，重复声明时返回已有的规则
+func (s *Schema) Key(key string, typ Type) *Rule {
+	for _, r := range s.rules {
+		if r.key == key {
+			r.typ = typ
+			return r
+		}
+	}
+	r := &Rule{key: key, typ: typ}
+	s.rules = append(s.rules, r)
+	return r
+}
+
+// ValidationError 校验失败的键，Origin为值的来源，使用默认值或键不存在时为空
+type ValidationError struct {
+	Key    string
+	Origin Origin
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	if e.Origin.File == "" {
+		return fmt.Sprintf("%s: %s", e.Key, e.Reason)
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Origin, e.Key, e.Reason)
+}
+
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	msg := make([]string, len(e))
+	for i, err := range e {
+		msg[i] = err.Error()
+	}
+	return strings.Join(msg, "\n")
+}
+
+// Validate 校验所有的键并一次返回全部错误，同时把默认值注册到f中
+func (s *Schema) Validate(f *FileReader) error {
+	var errs ValidationErrors
+	for _, r := range s.rules {
+		space, key, err := splitKey(r.key)
+		if err != nil {
+			errs = append(errs, &ValidationError{Key: r.key, Reason: err.Error()})
+			continue
+		}
+		if r.def != nil {
+			f.setDefault(space, key, *r.def)
+		}
+		c, ok, err := f.lookup(space, key)
+		origin, _ := f.Origin(r.key)
+		if err != nil {
+			errs = append(errs, &ValidationError{Key: r.key, Origin: origin, Reason: err.Error()})
+			continue
+		}
+		if !ok {
+			if r.required {
+				errs = append(errs, &ValidationError{Key: r.key, Reason: "required key is missing"})
+			}
+			continue
+		}
+		for _, reason := range r.check(fmt.Sprint(c.data)) {
+			errs = append(errs, &ValidationError{Key: r.key, Origin: origin, Reason: reason})
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// check 返回值违反的所有约束
+func (r *Rule) check(v string) []string {
+	var items []string
+	switch r.typ {
+	case TypeIntSlice, TypeFloatSlice, TypeStringSlice:
+		items = splitSlice(v)
+	default:
+		items = []string{v}
+	}
+
+	var reasons []string
+	for _, item := range items {
+		n, err := r.parse(item)
+		if err != nil {
+			reasons = append(reasons, fmt.Sprintf("%q is not a valid %s", item, r.typ))
+			continue
+		}
+		if r.typ == TypeStringSlice || r.typ == TypeString {
+			n = float64(utf8.RuneCountInString(item))
+		}
+		if r.typ != TypeIntSlice && r.typ != TypeFloatSlice && r.typ != TypeStringSlice {
+			reasons = append(reasons, r.checkRange(n)...)
+		}
+		if r.pattern != nil && !r.pattern.MatchString(item) {
+			reasons = append(reasons, fmt.Sprintf("%q does not match %s", item, r.pattern))
+		}
+		if len(r.enum) > 0 && !contains(r.enum, item) {
+			reasons = append(reasons, fmt.Sprintf("%q is not one of [%s]", item, strings.Join(r.enum, ",")))
+		}
+	}
+	if r.typ == TypeIntSlice || r.typ == TypeFloatSlice || r.typ == TypeStringSlice {
+		reasons = append(reasons, r.checkRange(float64(len(items)))...)
+	}
+	return reasons
+}
+
+func (r *Rule) parse(v string) (float64, error) {
+	switch r.typ {
+	case TypeInt, TypeIntSlice:
+		n, err := strconv.ParseInt(v, 10, 64)
+		return float64(n), err
+	case TypeFloat, TypeFloatSlice:
+		return strconv.ParseFloat(v, 64)
+	case TypeBool:
+		_, err := (&conv{data: v}).GetAsBool()
+		return 0, err
+	case TypeDuration:
+		d, err := (&conv{data: v}).GetAsDuration()
+		return float64(d), err
+	case TypeSize:
+		n, err := (&conv{data: v}).GetAsSize()
+		return float64(n), err
+	case TypeTime:
+		_, err := (&conv{data: v}).GetAsTime()
+		return 0, err
+	case TypeURL:
+		_, err := (&conv{data: v}).GetAsURL()
+		return 0, err
+	case TypeIP:
+		_, err := (&conv{data: v}).GetAsIP()
+		return 0, err
+	case TypeCIDR:
+		_, err := (&conv{data: v}).GetAsCIDR()
+		return 0, err
+	}
+	return 0, nil
+}
+
+func (r *Rule) checkRange(n float64) []string {
+	var reasons []string
+	if r.min != nil && n < *r.min {
+		reasons = append(reasons, fmt.Sprintf("%v is less than min %v", n, *r.min))
+	}
+	if r.max != nil && n > *r.max {
+		reasons = append(reasons, fmt.Sprintf("%v is greater than max %v", n, *r.max))
+	}
+	return reasons
+}
+
+func contains(arr []string, s string) bool {
+	for _, v := range arr {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
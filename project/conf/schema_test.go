@@ -0,0 +1,77 @@
+package conf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const schemaIni = `[user]
+username = admin
+port = 80a
+mode = turbo
+arrInt = [1,2,x]
+ratio = 1.5
+`
+
+func TestSchema_Validate(t *testing.T) {
+	f, name := openTemp(t, schemaIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	schema := NewSchema()
+	schema.Key("user::username", TypeString).Required().Match(`^[a-z]+$`).Range(3, 16)
+	schema.Key("user::port", TypeInt).Required().Range(1, 65535)
+	schema.Key("user::mode", TypeString).Enum("fast", "slow")
+	schema.Key("user::arrInt", TypeIntSlice).Max(2)
+	schema.Key("user::ratio", TypeFloat).Max(1)
+	schema.Key("user::password", TypeString).Required()
+	schema.Key("user::timeout", TypeInt).Default("30")
+
+	err := schema.Validate(f)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("expect ValidationErrors got %v", err)
+	}
+	expect := []struct {
+		key  string
+		line uint64
+	}{
+		{"user::port", 3},
+		{"user::mode", 4},
+		{"user::arrInt", 5},
+		{"user::arrInt", 5},
+		{"user::ratio", 6},
+		{"user::password", 0},
+	}
+	if len(errs) != len(expect) {
+		t.Fatalf("expect %d errors got:\n%v", len(expect), errs)
+	}
+	for i, e := range errs {
+		if e.Key != expect[i].key || e.Origin.Line != expect[i].line {
+			t.Errorf("expect %s at line %d got %s", expect[i].key, expect[i].line, e)
+		}
+	}
+
+	if v := f.Get("user::timeout").GetAsInt(); v != 30 {
+		t.Errorf("default not applied, got %d", v)
+	}
+	if v := f.Get("user::port").GetAsInt(); v != 0 {
+		t.Errorf("invalid int should be 0, got %d", v)
+	}
+}
+
+func TestSchema_ValidateOK(t *testing.T) {
+	f, name := openTemp(t, "[user]\nport = 8080\nneedInit = yes\n")
+	defer os.RemoveAll(filepath.Dir(name))
+
+	schema := NewSchema()
+	schema.Key("user::port", TypeInt).Required().Range(1, 65535)
+	schema.Key("user::needInit", TypeBool)
+	schema.Key("user::mode", TypeString).Default("fast").Enum("fast", "slow")
+	if err := schema.Validate(f); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("user::mode").GetAsString(); v != "fast" {
+		t.Errorf("expect fast got %s", v)
+	}
+}
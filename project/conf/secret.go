@@ -0,0 +1,360 @@
+package conf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+const secretPrefix = "enc:v1:"
+
+const (
+	// SecretKeyEnv 保存base64编码的密钥的环境变量
+	SecretKeyEnv = "CONF_SECRET_KEY"
+	// SecretKeyFileEnv 保存密钥文件路径的环境变量
+	SecretKeyFileEnv = "CONF_SECRET_KEY_FILE"
+)
+
+var ErrNoSecretKey = errors.New("secret key not set, use SetSecretKey or " + SecretKeyEnv + "/" + SecretKeyFileEnv)
+
+// NewSecretKey 生成随机的256位密钥，返回base64编码的字符串，可以直接写入密钥文件或环境变量
+func NewSecretKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// ParseSecretKey 解析base64编码的256位密钥，忽略首尾的空白
+func ParseSecretKey(data []byte) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid secret key: %v", err)
+	}
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid secret key: expect 32 bytes got %d", len(key))
+	}
+	return key, nil
+}
+
+// ReadSecretKey 从密钥文件中读取密钥
+func ReadSecretKey(path string) ([]byte, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := ParseSecretKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return key, nil
+}
+
+// DefaultSecretKey 依次从环境变量CONF_SECRET_KEY以及CONF_SECRET_KEY_FILE指定的文件中读取密钥
+func DefaultSecretKey() ([]byte, error) {
+	if v, ok := os.LookupEnv(SecretKeyEnv); ok {
+		key, err := ParseSecretKey([]byte(v))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", SecretKeyEnv, err)
+		}
+		return key, nil
+	}
+	if path, ok := os.LookupEnv(SecretKeyFileEnv); ok {
+		return ReadSecretKey(path)
+	}
+	return nil, ErrNoSecretKey
+}
+
+// IsEncrypted 值是否为enc:v1:形式的密文
+func IsEncrypted(v string) bool {
+	return strings.HasPrefix(v, secretPrefix)
+}
+
+// Encrypt 使用AES-GCM加密，返回"enc:v1:"加上base64编码的nonce和密文
+func Encrypt(key []byte, plain string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(plain), nil)
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密Encrypt返回的字符串，密钥错误或者密文被修改时返回错误
+func Decrypt(key []byte, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("not an encrypted value")
+	}
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(value, secretPrefix))
+	if err != nil {
+		return "", fmt.Errorf("invalid encrypted value: %v", err)
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("invalid encrypted value: too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", errors.New("decrypt failed: wrong key or corrupted value")
+	}
+	return string(plain), nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("invalid secret key: expect 32 bytes got %d", len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// SetSecretKey 设置解密使用的密钥，没有设置时使用DefaultSecretKey
+func (f *FileReader) SetSecretKey(key []byte) error {
+	if f.root != nil {
+		return f.root.SetSecretKey(key)
+	}
+	if len(key) != 32 {
+		return fmt.Errorf("invalid secret key: expect 32 bytes got %d", len(key))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.secret = key
+	return nil
+}
+
+func (f *FileReader) secretKey() ([]byte, error) {
+	if f.root != nil {
+		return f.root.secretKey()
+	}
+	f.mu.RLock()
+	key := f.secret
+	f.mu.RUnlock()
+	if key != nil {
+		return key, nil
+	}
+	return DefaultSecretKey()
+}
+
+func (f *FileReader) decrypt(value string) (string, error) {
+	key, err := f.secretKey()
+	if err != nil {
+		return "", err
+	}
+	return Decrypt(key, value)
+}
+
+// SetSecret 加密plain后通过Set写入
+func (f *FileReader) SetSecret(str string, plain string) error {
+	key, err := f.secretKey()
+	if err != nil {
+		return err
+	}
+	value, err := Encrypt(key, plain)
+	if err != nil {
+		return err
+	}
+	return f.Set(str, value)
+}
+
+// sealed 值是密文或者引用了密文时返回未解密、未替换的原始值，用于Dump等需要隐藏明文的地方
+func (f *FileReader) sealed(section, key string) (string, bool) {
+	if f.root != nil {
+		return f.root.sealed(f.scoped(section), key)
+	}
+	if !f.sensitive(section, key, nil) {
+		return "", false
+	}
+	data, _ := f.raw(section, key)
+	return data.(string), true
+}
+
+func (f *FileReader) sensitive(section, key string, stack []string) bool {
+	data, ok := f.raw(section, key)
+	str, isString := data.(string)
+	if !ok || !isString {
+		return false
+	}
+	if IsEncrypted(str) {
+		return true
+	}
+	name := f.name(section) + "::" + f.name(key)
+	for _, s := range stack {
+		if s == name {
+			return false
+		}
+	}
+	return f.refersSecret(str, append(stack, name))
+}
+
+// refersSecret 字符串中的${section::key}以及默认值中是否引用了密文
+func (f *FileReader) refersSecret(str string, stack []string) bool {
+	for {
+		idx := strings.Index(str, "${")
+		if idx < 0 {
+			return false
+		}
+		end := closeBrace(str[idx+2:])
+		if end < 0 {
+			return false
+		}
+		name, def := str[idx+2:idx+2+end], ""
+		str = str[idx+3+end:]
+		if i := strings.Index(name, ":-"); i >= 0 {
+			name, def = name[:i], name[i+2:]
+		}
+		if strings.Contains(name, "::") {
+			space, key, err := splitKey(strings.TrimSpace(name))
+			if err == nil && f.sensitive(space, key, stack) {
+				return true
+			}
+		}
+		if f.refersSecret(def, stack) {
+			return true
+		}
+	}
+}
+
+// Rotate 使用当前的密钥解密f自身文件中所有加密的值，再使用newKey重新加密，返回修改的值的数量，
+// 全部成功后newKey成为当前的密钥，任何一个值解密失败时不做修改。
+// include的文件以及Load覆盖的文件中的值不会被修改，需要通过SecretFiles找到这些文件分别轮换
+func (f *FileReader) Rotate(newKey []byte) (int, error) {
+	if f.root != nil {
+		return f.root.Rotate(newKey)
+	}
+	if len(newKey) != 32 {
+		return 0, fmt.Errorf("invalid secret key: expect 32 bytes got %d", len(newKey))
+	}
+	key, err := f.secretKey()
+	if err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	// 按原始的行处理，只包含本文件中的值，并且不会被插值或者环境变量影响
+	var lines []*line
+	var values []string
+	for _, s := range f.sections {
+		for _, l := range s.lines {
+			if l.kind != keyLine || !IsEncrypted(l.value) {
+				continue
+			}
+			plain, err := Decrypt(key, l.value)
+			if err != nil {
+				return 0, fmt.Errorf("%s:%d: %v", f.fileName, l.lineNo, err)
+			}
+			lines, values = append(lines, l), append(values, plain)
+		}
+	}
+
+	rotated := make(map[string]string, len(lines))
+	for i, l := range lines {
+		value, err := Encrypt(newKey, values[i])
+		if err != nil {
+			return 0, err
+		}
+		rotated[l.value] = value
+	}
+	for _, s := range f.sections {
+		for _, l := range s.lines {
+			if value, ok := rotated[l.value]; ok && l.kind == keyLine {
+				l.text, l.value = "", value
+				s.value.Put(l.key, value)
+			}
+		}
+	}
+	// 密文中带有随机数，相同的密文只会来自同一行
+	for _, v := range f.data {
+		for k, d := range v.value {
+			if str, ok := d.(string); ok && rotated[str] != "" {
+				v.value[k] = rotated[str]
+			}
+		}
+	}
+	f.secret = newKey
+	return len(lines), nil
+}
+
+// EncryptKey 使用当前的密钥加密"section::key"在f自身文件中的原始值，已经加密的值不做修改，
+// 值来自include的文件或者覆盖的文件时返回错误
+func (f *FileReader) EncryptKey(str string) error {
+	space, key, err := splitKey(str)
+	if err != nil {
+		return err
+	}
+	if f.root != nil {
+		return f.root.EncryptKey(f.scoped(space) + "::" + key)
+	}
+	space, key = f.name(space), f.name(key)
+	f.mu.RLock()
+	value := f.data[space]
+	d, ok := value.Get(key)
+	origin := f.origin[space+"::"+key]
+	f.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%s: no such key", str)
+	}
+	if origin.File != f.fileName {
+		return fmt.Errorf("%s: defined in %s, encrypt it in that file", str, origin)
+	}
+	plain, isString := d.data.(string)
+	if !isString {
+		return fmt.Errorf("%s: not a string value", str)
+	}
+	if IsEncrypted(plain) {
+		return nil
+	}
+	return f.SetSecret(str, plain)
+}
+
+// SecretFiles 返回包含密文的文件，f自身的文件排在最前面，轮换密钥时需要分别轮换这些文件
+func (f *FileReader) SecretFiles() []string {
+	if f.root != nil {
+		return f.root.SecretFiles()
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var files []string
+	seen := map[string]bool{f.fileName: true}
+	for _, s := range f.sections {
+		for _, l := range s.lines {
+			if l.kind == keyLine && IsEncrypted(l.value) && files == nil {
+				files = []string{f.fileName}
+			}
+		}
+	}
+	var others []string
+	for name, o := range f.origin {
+		space, key, err := splitKey(name)
+		if err != nil || seen[o.File] {
+			continue
+		}
+		value := f.data[space]
+		d, _ := value.Get(key)
+		if str, ok := d.data.(string); ok && IsEncrypted(str) {
+			seen[o.File] = true
+			others = append(others, o.File)
+		}
+	}
+	sort.Strings(others)
+	return append(files, others...)
+}
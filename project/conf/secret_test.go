@@ -0,0 +1,171 @@
+package conf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newKey(t *testing.T) []byte {
+	str, err := NewSecretKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := ParseSecretKey([]byte(str + "\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestEncrypt(t *testing.T) {
+	key := newKey(t)
+	enc, err := Encrypt(key, "admin123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(enc) || strings.Contains(enc, "admin123") {
+		t.Fatalf("got %s", enc)
+	}
+	if other, _ := Encrypt(key, "admin123"); other == enc {
+		t.Error("nonce should be random")
+	}
+	if plain, err := Decrypt(key, enc); err != nil || plain != "admin123" {
+		t.Errorf("got %s %v", plain, err)
+	}
+	if _, err := Decrypt(newKey(t), enc); err == nil {
+		t.Error("expect wrong key error")
+	}
+	if _, err := Decrypt(key, enc[:len(enc)-2]+"AA"); err == nil {
+		t.Error("expect corrupted error")
+	}
+	if _, err := ParseSecretKey([]byte("c2hvcnQ=")); err == nil {
+		t.Error("expect short key error")
+	}
+}
+
+func TestFileReader_Secret(t *testing.T) {
+	key := newKey(t)
+	enc, _ := Encrypt(key, "admin123")
+	dir := writeFiles(t, map[string]string{
+		"spider.ini": "[user]\nusername = admin\n# 密码\npassword = " + enc + "\nproxy = ${user::password}@127.0.0.1\n",
+	})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spider.ini")
+	f, err := NewParser(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Unsetenv(SecretKeyEnv)
+	os.Unsetenv(SecretKeyFileEnv)
+	if _, ok := f.Lookup("user::password"); ok {
+		t.Error("expect lookup failure without key")
+	}
+	keyFile := filepath.Join(dir, "secret.key")
+	ioutil.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600)
+	os.Setenv(SecretKeyFileEnv, keyFile)
+	defer os.Unsetenv(SecretKeyFileEnv)
+	if v := f.Get("user::password").GetAsString(); v != "admin123" {
+		t.Errorf("expect admin123 got %s", v)
+	}
+	if v := f.Get("user::proxy").GetAsString(); v != "admin123@127.0.0.1" {
+		t.Errorf("got %s", v)
+	}
+
+	var buf bytes.Buffer
+	f.Dump(&buf)
+	if strings.Contains(buf.String(), "admin123") {
+		t.Errorf("dump leaks secret\n%s", buf.String())
+	}
+
+	if err := f.SetSecret("user::token", "t0ken"); err != nil {
+		t.Fatal(err)
+	}
+	next := newKey(t)
+	if n, err := f.Rotate(next); err != nil || n != 2 {
+		t.Fatalf("got %d %v", n, err)
+	}
+	if err := f.Save(""); err != nil {
+		t.Fatal(err)
+	}
+
+	f, _ = NewParser(path)
+	if err := f.SetSecretKey(next); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("user::password").GetAsString(); v != "admin123" {
+		t.Errorf("expect admin123 got %s", v)
+	}
+	if v := f.Get("user::token").GetAsString(); v != "t0ken" {
+		t.Errorf("expect t0ken got %s", v)
+	}
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), enc) || !strings.Contains(string(data), "# 密码\n") {
+		t.Errorf("got\n%s", data)
+	}
+	if _, err := f.Rotate(newKey(t)); err != nil {
+		t.Fatal(err)
+	}
+	f.SetSecretKey(key)
+	if _, err := f.Rotate(next); err == nil {
+		t.Error("expect decrypt error with wrong key")
+	}
+}
+
+// Rotate和EncryptKey只修改本文件中的值，include的文件需要分别处理
+func TestFileReader_SecretInclude(t *testing.T) {
+	key := newKey(t)
+	enc, _ := Encrypt(key, "admin123")
+	dir := writeFiles(t, map[string]string{
+		"spider.ini":  "%include secrets.ini\n\n[app]\ndsn = root:${db::password}@127.0.0.1\n",
+		"secrets.ini": "[db]\npassword = " + enc + "\n",
+	})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spider.ini")
+	f, err := NewParser(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.SetSecretKey(key)
+
+	if files := f.SecretFiles(); len(files) != 1 || files[0] != filepath.Join(dir, "secrets.ini") {
+		t.Errorf("got %v", files)
+	}
+	if err := f.EncryptKey("db::password"); err == nil {
+		t.Error("expect error for included key")
+	}
+	if err := f.EncryptKey("app::dsn"); err != nil {
+		t.Fatal(err)
+	}
+	next := newKey(t)
+	if n, err := f.Rotate(next); err != nil || n != 1 {
+		t.Fatalf("got %d %v", n, err)
+	}
+	if err := f.Save(""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(data), "[db]") || strings.Contains(string(data), "127.0.0.1") {
+		t.Errorf("got\n%s", data)
+	}
+	if files := f.SecretFiles(); len(files) != 2 || files[0] != path {
+		t.Errorf("got %v", files)
+	}
+	// 加密的是插值之前的原始值
+	f, _ = NewParser(path)
+	raw, _ := f.raw("app", "dsn")
+	if plain, err := Decrypt(next, raw.(string)); err != nil || plain != "root:${db::password}@127.0.0.1" {
+		t.Errorf("got %s %v", plain, err)
+	}
+	// secrets.ini没有被修改，仍然使用原来的密钥
+	f.SetSecretKey(key)
+	if v := f.Get("db::password").GetAsString(); v != "admin123" {
+		t.Errorf("expect admin123 got %s", v)
+	}
+}
@@ -0,0 +1,55 @@
+package conf
+
+import (
+	"errors"
+	"strings"
+)
+
+// errSubView Sub返回的视图不支持的操作
+var errSubView = errors.New("not supported on a Sub view")
+
+// parentSection 返回上一级段落名，例如"spider.downloader"的上一级为"spider"
+func parentSection(name string) (string, bool) {
+	idx := strings.LastIndex(name, ".")
+	if idx < 0 {
+		return "", false
+	}
+	return name[:idx], true
+}
+
+func childSection(parent, name string) string {
+	if parent == "" {
+		return name
+	}
+	if name == "" {
+		return parent
+	}
+	return parent + "." + name
+}
+
+func (f *FileReader) scoped(section string) string {
+	return childSection(f.prefix, section)
+}
+
+// relative 返回section相对于视图根段落的名字，section不在视图中时返回false
+func (f *FileReader) relative(section string) (string, bool) {
+	switch {
+	case f.prefix == "":
+		return section, true
+	case section == f.prefix:
+		return "", true
+	case strings.HasPrefix(section, f.prefix+"."):
+		return section[len(f.prefix)+1:], true
+	}
+	return "", false
+}
+
+// Sub 返回以prefix为根的视图，视图中的"::key"对应prefix::key，"downloader::key"对应prefix.downloader::key，
+// 视图与原始的FileReader共享数据，Set、Delete、AddSection等修改原始的FileReader，
+// WriteTo、Save、Reload、Watch等作用于原始的FileReader对应的整个文件，视图不支持Open
+func (f *FileReader) Sub(prefix string) *FileReader {
+	if f.root != nil {
+		return f.root.Sub(f.scoped(prefix))
+	}
+	return &FileReader{root: f, prefix: prefix}
+}
@@ -0,0 +1,140 @@
+package conf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const subIni = `[spider]
+timeout = 30
+worker = 2
+agent = golang
+
+[spider.downloader]
+worker = 8
+
+[spider.downloader.proxy]
+host = 127.0.0.1
+`
+
+func TestFileReader_Inherit(t *testing.T) {
+	f, name := openTemp(t, subIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	cases := map[string]string{
+		"spider.downloader::timeout":      "30",
+		"spider.downloader::worker":       "8",
+		"spider.downloader.proxy::worker": "8",
+		"spider.downloader.proxy::agent":  "golang",
+		"spider.analyzer::worker":         "2",
+		"spider.downloader.proxy::host":   "127.0.0.1",
+	}
+	for k, v := range cases {
+		if res := f.Get(k).GetAsString(); res != v {
+			t.Errorf("%s: expect %s got %s", k, v, res)
+		}
+	}
+	if o, ok := f.Origin("spider.downloader.proxy::worker"); !ok || o.Line != 7 {
+		t.Errorf("got %s", o)
+	}
+	if _, ok, _ := f.lookup("spider.downloader", "host"); ok {
+		t.Error("child value should not be visible from parent")
+	}
+}
+
+func TestFileReader_Sub(t *testing.T) {
+	f, name := openTemp(t, subIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	spider := f.Sub("spider")
+	if v := spider.Get("::timeout").GetAsString(); v != "30" {
+		t.Errorf("expect 30 got %s", v)
+	}
+	if v := spider.Get("downloader::worker").GetAsString(); v != "8" {
+		t.Errorf("expect 8 got %s", v)
+	}
+	proxy := spider.Sub("downloader.proxy")
+	if v := proxy.Get("::agent").GetAsString(); v != "golang" {
+		t.Errorf("expect golang got %s", v)
+	}
+
+	var cfg struct {
+		Timeout    int `ini:"timeout"`
+		Downloader struct {
+			Worker int `ini:"worker"`
+			Proxy  struct {
+				Host    string `ini:"host"`
+				Timeout int    `ini:"timeout"`
+			} `ini:"proxy"`
+		} `ini:"downloader"`
+	}
+	if err := spider.Unmarshal("", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Timeout != 30 || cfg.Downloader.Worker != 8 || cfg.Downloader.Proxy.Host != "127.0.0.1" || cfg.Downloader.Proxy.Timeout != 30 {
+		t.Errorf("got %+v", cfg)
+	}
+
+	if err := spider.Set("analyzer::worker", "1"); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("spider.analyzer::worker").GetAsString(); v != "1" {
+		t.Errorf("expect 1 got %s", v)
+	}
+}
+
+// 视图上的修改、输出和重新加载都作用于原始的FileReader
+func TestFileReader_SubForward(t *testing.T) {
+	f, name := openTemp(t, subIni)
+	defer os.RemoveAll(filepath.Dir(name))
+	spider := f.Sub("spider")
+
+	if err := spider.AddSection("analyzer"); err != nil {
+		t.Fatal(err)
+	}
+	if err := spider.DeleteSection("downloader.proxy"); err != nil {
+		t.Fatal(err)
+	}
+	if err := spider.Save(""); err != nil {
+		t.Fatal(err)
+	}
+	var view, root bytes.Buffer
+	spider.WriteTo(&view)
+	f.WriteTo(&root)
+	data, _ := ioutil.ReadFile(name)
+	if view.String() != root.String() || string(data) != root.String() ||
+		!strings.Contains(root.String(), "[spider.analyzer]") || strings.Contains(root.String(), "[spider.downloader.proxy]") {
+		t.Errorf("got\n%s", data)
+	}
+
+	var events []Event
+	spider.Subscribe("downloader", func(e Event) { events = append(events, e) })
+	ioutil.WriteFile(name, []byte(subIni+"\n[other]\nworker = 1\n"), 0600)
+	if err := spider.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Errorf("got %+v", events)
+	}
+	ioutil.WriteFile(name, []byte(strings.Replace(subIni, "worker = 8", "worker = 16", 1)), 0600)
+	if err := spider.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Section != "downloader" || events[0].New.GetAsString() != "16" {
+		t.Errorf("got %+v", events)
+	}
+
+	os.Setenv("SPIDER_TIMEOUT", "60")
+	defer os.Unsetenv("SPIDER_TIMEOUT")
+	spider.EnvOverride("")
+	if v := f.Get("spider::timeout").GetAsString(); v != "60" {
+		t.Errorf("expect 60 got %s", v)
+	}
+	if err := spider.Open(name); err == nil {
+		t.Error("expect error when opening a file on a view")
+	}
+}
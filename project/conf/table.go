@@ -0,0 +1,274 @@
+package conf
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// tableIndex 拆分"name[i]"形式的段落名
+func tableIndex(name string) (base string, idx int, ok bool) {
+	if !strings.HasSuffix(name, "]") {
+		return name, 0, false
+	}
+	start := strings.LastIndex(name, "[")
+	if start <= 0 {
+		return name, 0, false
+	}
+	idx, err := strconv.Atoi(name[start+1 : len(name)-1])
+	if err != nil || idx < 0 {
+		return name, 0, false
+	}
+	return name[:start], idx, true
+}
+
+func tableName(base string, idx int) string {
+	return fmt.Sprintf("%s[%d]", base, idx)
+}
+
+// occurrences 返回段落每次出现的位置
+func (f *FileReader) occurrences(name string) []*section {
+	var occ []*section
+	for i, s := range f.sections {
+		if s.name == name && (i > 0 || name != "") {
+			occ = append(occ, s)
+		}
+	}
+	return occ
+}
+
+// isTable 段落使用[[name]]声明或者出现了多次
+func (f *FileReader) isTable(name string) bool {
+	occ := f.occurrences(name)
+	return len(occ) > 1 || len(occ) == 1 && occ[0].table
+}
+
+// indexTables 为重复出现的段落建立"name[i]"的索引，name为空时处理所有段落
+func (f *FileReader) indexTables(name string) {
+	done := make(map[string]bool)
+	for _, s := range f.sections {
+		if s.name == "" || done[s.name] || name != "" && s.name != name {
+			continue
+		}
+		done[s.name] = true
+		if !f.isTable(s.name) {
+			continue
+		}
+		for i, occ := range f.occurrences(s.name) {
+			table := tableName(s.name, i)
+			f.data[table] = occ.value
+			for _, l := range occ.lines {
+				if l.kind == keyLine {
+					f.origin[table+"::"+l.key] = Origin{File: f.fileName, Line: l.lineNo}
+				}
+			}
+		}
+	}
+}
+
+func (f *FileReader) clearTables(base string) {
+	for k := range f.data {
+		if b, _, ok := tableIndex(k); ok && b == base {
+			delete(f.data, k)
+		}
+	}
+	for k := range f.origin {
+		space, _, _ := splitKey(k)
+		if b, _, ok := tableIndex(space); ok && b == base {
+			delete(f.origin, k)
+		}
+	}
+}
+
+// remerge 重新计算合并后的值，最后一次出现的段落中的值生效
+func (f *FileReader) remerge(base, key string) {
+	merged := f.data[base]
+	if merged.value == nil {
+		merged = *NewValue()
+	}
+	defer func() { f.data[base] = merged }()
+
+	occ := f.occurrences(base)
+	for i := len(occ) - 1; i >= 0; i-- {
+		if v, ok := occ[i].value.value[key]; ok {
+			if _, has := merged.value[key]; !has {
+				merged.cap++
+			}
+			merged.value[key] = v
+			f.origin[base+"::"+key] = f.origin[tableName(base, i)+"::"+key]
+			return
+		}
+	}
+	merged.Delete(key)
+	delete(f.origin, base+"::"+key)
+}
+
+// AppendTable 在文件末尾添加一个[[name]]段落，返回它的下标，之后可以通过Set("name[i]::key")设置其中的值
+func (f *FileReader) AppendTable(name string) (int, error) {
+	if f.root != nil {
+		return f.root.AppendTable(f.scoped(name))
+	}
+	name = f.name(strings.TrimSpace(name))
+	if name == "" {
+		return 0, fmt.Errorf("table name is empty")
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.init()
+	last := f.sections[len(f.sections)-1]
+	if n := len(last.lines); n > 0 && last.lines[n-1].kind != blankLine {
+		last.lines = append(last.lines, &line{kind: blankLine})
+	}
+	f.sections = append(f.sections, &section{
+		name:  name,
+		lines: []*line{{kind: sectionLine, text: "[[" + name + "]]"}},
+		value: *NewValue(),
+		table: true,
+	})
+	if _, ok := f.data[name]; !ok {
+		f.data[name] = *NewValue()
+	}
+	f.indexTables(name)
+	return len(f.occurrences(name)) - 1, nil
+}
+
+// Tables 返回段落每次出现时的值，只出现一次的段落返回只有一个元素的切片，不存在时返回nil
+func (f *FileReader) Tables(name string) []Value {
+	if f.root != nil {
+		return f.root.Tables(f.scoped(name))
+	}
+	name = f.name(name)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	if f.isTable(name) {
+		occ := f.occurrences(name)
+		res := make([]Value, len(occ))
+		for i, s := range occ {
+			res[i] = s.value
+		}
+		return res
+	}
+	if v, ok := f.data[name]; ok {
+		return []Value{v}
+	}
+	return nil
+}
+
+// Sections 按文件中的顺序返回所有段落名，不包含"name[i]"，include或者Load进来的段落按字母顺序排在最后
+func (f *FileReader) Sections() []string {
+	if f.root != nil {
+		var res []string
+		for _, name := range f.root.Sections() {
+			if strings.HasPrefix(name, f.prefix+".") {
+				res = append(res, name[len(f.prefix)+1:])
+			} else if name == f.prefix {
+				res = append(res, "")
+			}
+		}
+		return res
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	var res, extra []string
+	done := make(map[string]bool)
+	for i, s := range f.sections {
+		if done[s.name] || i == 0 && len(f.data[""].value) == 0 {
+			continue
+		}
+		done[s.name] = true
+		res = append(res, s.name)
+	}
+	for name := range f.data {
+		if _, _, ok := tableIndex(name); !ok && !done[name] {
+			extra = append(extra, name)
+		}
+	}
+	sort.Strings(extra)
+	return append(res, extra...)
+}
+
+// Keys 按文件中的顺序返回段落中的键，不在文件中的键按字母顺序排在最后
+func (f *FileReader) Keys(name string) []string {
+	if f.root != nil {
+		return f.root.Keys(f.scoped(name))
+	}
+	name = f.name(name)
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	var occ []*section
+	if _, _, ok := tableIndex(name); ok {
+		if s := f.section(name); s != nil {
+			occ = []*section{s}
+		}
+	} else if name == "" {
+		occ = f.sections[:1]
+	} else {
+		occ = f.occurrences(name)
+	}
+
+	var res, extra []string
+	done := make(map[string]bool)
+	for _, s := range occ {
+		for _, l := range s.lines {
+			if l.kind == keyLine && !done[l.key] {
+				done[l.key] = true
+				res = append(res, l.key)
+			}
+		}
+	}
+	for k := range f.data[name].value {
+		if !done[k] {
+			extra = append(extra, k)
+		}
+	}
+	sort.Strings(extra)
+	return append(res, extra...)
+}
+
+// Range 按Sections和Keys的顺序遍历所有的值，重复出现的段落按"name[i]"分别遍历，fn返回false时停止
+func (f *FileReader) Range(fn func(section, key string, value *conv) bool) {
+	f.each(func(space, key string) bool {
+		c, ok := f.Lookup(space + "::" + key)
+		return !ok || fn(space, key, c)
+	})
+}
+
+// each 按文件中的顺序遍历所有的段落和键，重复的段落按下标展开
+func (f *FileReader) each(fn func(section, key string) bool) {
+	for _, name := range f.Sections() {
+		spaces := []string{name}
+		if f.tableDeclared(name) {
+			spaces = spaces[:0]
+			for i := range f.Tables(name) {
+				spaces = append(spaces, tableName(name, i))
+			}
+		}
+		for _, space := range spaces {
+			for _, key := range f.Keys(space) {
+				if !fn(space, key) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func (f *FileReader) tableCount(name string) int {
+	if f.root != nil {
+		return f.root.tableCount(f.scoped(name))
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return len(f.occurrences(f.name(name)))
+}
+
+func (f *FileReader) tableDeclared(name string) bool {
+	if f.root != nil {
+		return f.root.tableDeclared(f.scoped(name))
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return f.isTable(f.name(name))
+}
@@ -0,0 +1,170 @@
+package conf
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const tableIni = `[spider]
+worker = 2
+
+[[downloader]]
+name = fast
+timeout = 10s
+
+[[downloader]]
+name = slow
+# retry
+retry = 3
+
+[seed]
+url = http://a.com
+
+[seed]
+url = http://b.com
+`
+
+func TestFileReader_Tables(t *testing.T) {
+	f, name := openTemp(t, tableIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	if s := f.Sections(); !reflect.DeepEqual(s, []string{"spider", "downloader", "seed"}) {
+		t.Errorf("got %v", s)
+	}
+	downloaders := f.Tables("downloader")
+	if len(downloaders) != 2 {
+		t.Fatalf("expect 2 tables got %d", len(downloaders))
+	}
+	if v, _ := downloaders[1].Get("name"); v.GetAsString() != "slow" {
+		t.Errorf("got %s", v.GetAsString())
+	}
+	if len(f.Tables("seed")) != 2 || len(f.Tables("spider")) != 1 || f.Tables("none") != nil {
+		t.Error("wrong table count")
+	}
+	if v := f.Get("downloader[0]::timeout").GetAsString(); v != "10s" {
+		t.Errorf("got %s", v)
+	}
+	if v := f.Get("seed::url").GetAsString(); v != "http://b.com" {
+		t.Errorf("last section should win, got %s", v)
+	}
+	if o, _ := f.Origin("downloader[1]::retry"); o.Line != 11 {
+		t.Errorf("got %s", o)
+	}
+	if k := f.Keys("downloader"); !reflect.DeepEqual(k, []string{"name", "timeout", "retry"}) {
+		t.Errorf("got %v", k)
+	}
+
+	var got []string
+	f.Range(func(section, key string, value *conv) bool {
+		got = append(got, section+"::"+key+"="+value.GetAsString())
+		return true
+	})
+	expect := []string{
+		"spider::worker=2",
+		"downloader[0]::name=fast",
+		"downloader[0]::timeout=10s",
+		"downloader[1]::name=slow",
+		"downloader[1]::retry=3",
+		"seed[0]::url=http://a.com",
+		"seed[1]::url=http://b.com",
+	}
+	if !reflect.DeepEqual(got, expect) {
+		t.Errorf("got %v", got)
+	}
+
+	var cfg struct {
+		Downloaders []struct {
+			Name  string `ini:"name"`
+			Retry int    `ini:"retry" default:"1"`
+		} `ini:"downloader"`
+		Seeds []*struct {
+			URL string `ini:"url"`
+		} `ini:"seed"`
+	}
+	if err := f.Unmarshal("", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Downloaders) != 2 || cfg.Downloaders[0].Retry != 1 || cfg.Downloaders[1].Retry != 3 ||
+		len(cfg.Seeds) != 2 || cfg.Seeds[0].URL != "http://a.com" {
+		t.Errorf("got %+v", cfg)
+	}
+}
+
+func TestFileReader_TablesWrite(t *testing.T) {
+	f, name := openTemp(t, tableIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	if err := f.Set("downloader[0]::timeout", "5s"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("downloader[5]::timeout", "5s"); err == nil {
+		t.Error("set a missing table should fail")
+	}
+	if err := f.Set("seed::url", "http://c.com"); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("seed[1]::url").GetAsString(); v != "http://c.com" {
+		t.Errorf("set without index should change the last table, got %s", v)
+	}
+	// 键只在前面的段落中时修改原来的位置，不在最后一个段落中添加重复的键
+	if err := f.Set("downloader::timeout", "20s"); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("downloader[0]::timeout").GetAsString(); v != "20s" {
+		t.Errorf("expect 20s got %s", v)
+	}
+	if _, ok := f.Lookup("downloader[1]::timeout"); ok {
+		t.Error("timeout should not be added to the last table")
+	}
+	if err := f.DeleteSection("downloader[0]"); err != nil {
+		t.Fatal(err)
+	}
+	if v := f.Get("downloader[0]::name").GetAsString(); v != "slow" {
+		t.Errorf("got %s", v)
+	}
+	if _, ok := f.Lookup("downloader::timeout"); ok {
+		t.Error("timeout should be removed with the table")
+	}
+	idx, err := f.AppendTable("downloader")
+	if err != nil || idx != 1 {
+		t.Fatalf("got %d %v", idx, err)
+	}
+	f.Set("downloader[1]::name", "proxy")
+	if v := f.Get("downloader::name").GetAsString(); v != "proxy" {
+		t.Errorf("got %s", v)
+	}
+
+	var buf bytes.Buffer
+	f.WriteTo(&buf)
+	expect := `[spider]
+worker = 2
+
+[[downloader]]
+name = slow
+# retry
+retry = 3
+
+[seed]
+url = http://a.com
+
+[seed]
+url = http://c.com
+
+[[downloader]]
+name = proxy
+`
+	if buf.String() != expect {
+		t.Errorf("unexpected content:\n%s", buf.String())
+	}
+
+	json, err := ParseString(`{"downloader": [{"name": "a"}, {"name": "b", "proxy": {"host": "h"}}]}`, JSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := json.Get("downloader[1].proxy::host").GetAsString(); v != "h" {
+		t.Errorf("got %s", v)
+	}
+}
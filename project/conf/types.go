@@ -0,0 +1,169 @@
+package conf
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func (c *conv) GetAsDuration() (time.Duration, error) {
+	if c.data == nil {
+		return 0, errors.New("the data is nil")
+	}
+	if d, ok := c.data.(time.Duration); ok {
+		return d, nil
+	}
+	return time.ParseDuration(strings.TrimSpace(c.GetAsString()))
+}
+
+var sizeUnits = map[string]float64{
+	"":   1,
+	"B":  1,
+	"K":  1 << 10,
+	"KB": 1 << 10,
+	"M":  1 << 20,
+	"MB": 1 << 20,
+	"G":  1 << 30,
+	"GB": 1 << 30,
+	"T":  1 << 40,
+	"TB": 1 << 40,
+}
+
+// GetAsSize 解析"10MB"、"512K"、"1.5GB"形式的大小，单位不区分大小写，按1024进制计算，可以带有i，例如MiB
+func (c *conv) GetAsSize() (int64, error) {
+	if c.data == nil {
+		return 0, errors.New("the data is nil")
+	}
+	if d, ok := c.data.(int); ok {
+		return int64(d), nil
+	}
+	s := strings.TrimSpace(c.GetAsString())
+	i := 0
+	for i < len(s) && (s[i] >= '0' && s[i] <= '9' || s[i] == '.') {
+		i++
+	}
+	unit := strings.Replace(strings.ToUpper(strings.TrimSpace(s[i:])), "IB", "B", 1)
+	mul, ok := sizeUnits[unit]
+	if !ok || i == 0 {
+		return 0, fmt.Errorf("can not conver %q as size", s)
+	}
+	n, err := strconv.ParseFloat(s[:i], 64)
+	if err != nil {
+		return 0, err
+	}
+	return int64(n * mul), nil
+}
+
+// GetAsTime 解析RFC3339格式的时间
+func (c *conv) GetAsTime() (time.Time, error) {
+	if c.data == nil {
+		return time.Time{}, errors.New("the data is nil")
+	}
+	if d, ok := c.data.(time.Time); ok {
+		return d, nil
+	}
+	return time.Parse(time.RFC3339, strings.TrimSpace(c.GetAsString()))
+}
+
+// GetAsURL 解析带有scheme和host的绝对地址
+func (c *conv) GetAsURL() (*url.URL, error) {
+	if c.data == nil {
+		return nil, errors.New("the data is nil")
+	}
+	s := strings.TrimSpace(c.GetAsString())
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("%q is not an absolute url", s)
+	}
+	return u, nil
+}
+
+func (c *conv) GetAsIP() (net.IP, error) {
+	if c.data == nil {
+		return nil, errors.New("the data is nil")
+	}
+	s := strings.TrimSpace(c.GetAsString())
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, fmt.Errorf("can not conver %q as ip", s)
+	}
+	return ip, nil
+}
+
+// GetAsCIDR 解析"192.168.1.0/24"形式的网段
+func (c *conv) GetAsCIDR() (*net.IPNet, error) {
+	if c.data == nil {
+		return nil, errors.New("the data is nil")
+	}
+	_, ipNet, err := net.ParseCIDR(strings.TrimSpace(c.GetAsString()))
+	return ipNet, err
+}
+
+// Lookup 与Get相同，但不会panic，键不存在、格式错误或者变量替换失败时返回false
+func (f *FileReader) Lookup(str string) (*conv, bool) {
+	space, key, err := splitKey(str)
+	if err != nil {
+		return &conv{}, false
+	}
+	c, ok, err := f.lookup(space, key)
+	if err != nil || !ok {
+		return &conv{}, false
+	}
+	return c, true
+}
+
+// GetOr 键不存在时返回def
+func (f *FileReader) GetOr(str string, def string) *conv {
+	if c, ok := f.Lookup(str); ok {
+		return c
+	}
+	return &conv{data: def}
+}
+
+// GetMap 将section中"name.sub = value"形式的键转换为map，str为"section::name"，
+// 上一级段落中的同名键也会被包含进来，子段落中的值优先
+func (f *FileReader) GetMap(str string) map[string]string {
+	res := make(map[string]string)
+	space, name, err := splitKey(str)
+	if err != nil {
+		return res
+	}
+	if f.root != nil {
+		return f.root.GetMap(f.scoped(space) + "::" + name)
+	}
+	space, name = f.name(space), f.name(name)
+
+	chain := []string{space}
+	for s := space; ; {
+		parent, ok := parentSection(s)
+		if !ok {
+			break
+		}
+		chain = append([]string{parent}, chain...)
+		s = parent
+	}
+	prefix := name + "."
+	for _, s := range chain {
+		var keys []string
+		f.mu.RLock()
+		for k := range f.data[s].value {
+			if strings.HasPrefix(k, prefix) && len(k) > len(prefix) {
+				keys = append(keys, k)
+			}
+		}
+		f.mu.RUnlock()
+		for _, k := range keys {
+			if c, ok, err := f.lookup(s, k); err == nil && ok {
+				res[k[len(prefix):]] = c.GetAsString()
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,120 @@
+package conf
+
+import (
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const typesIni = `[spider]
+timeout = 30s
+buffer = 10MB
+start = 2019-06-03T10:00:00+08:00
+seed = https://search.51job.com/list?a=b
+proxy = 127.0.0.1
+allow = 192.168.1.0/24
+headers.User-Agent = golang
+headers.Accept = text/html
+
+[spider.downloader]
+headers.Accept = */*
+`
+
+func TestConv_Types(t *testing.T) {
+	f, name := openTemp(t, typesIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	if d, err := f.Get("spider::timeout").GetAsDuration(); err != nil || d != 30*time.Second {
+		t.Errorf("got %v %v", d, err)
+	}
+	if n, err := f.Get("spider::buffer").GetAsSize(); err != nil || n != 10<<20 {
+		t.Errorf("got %v %v", n, err)
+	}
+	if tm, err := f.Get("spider::start").GetAsTime(); err != nil || tm.Hour() != 10 {
+		t.Errorf("got %v %v", tm, err)
+	}
+	if u, err := f.Get("spider::seed").GetAsURL(); err != nil || u.Host != "search.51job.com" {
+		t.Errorf("got %v %v", u, err)
+	}
+	if _, err := f.Get("spider::proxy").GetAsURL(); err == nil {
+		t.Error("relative url should fail")
+	}
+	if ip, err := f.Get("spider::proxy").GetAsIP(); err != nil || !ip.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Errorf("got %v %v", ip, err)
+	}
+	if n, err := f.Get("spider::allow").GetAsCIDR(); err != nil || !n.Contains(net.IPv4(192, 168, 1, 10)) {
+		t.Errorf("got %v %v", n, err)
+	}
+
+	sizes := map[string]int64{"512": 512, "1k": 1024, "1.5KiB": 1536, "2 GB": 2 << 30}
+	for s, n := range sizes {
+		if v, err := (&conv{data: s}).GetAsSize(); err != nil || v != n {
+			t.Errorf("%s: expect %d got %d %v", s, n, v, err)
+		}
+	}
+	if _, err := (&conv{data: "10XB"}).GetAsSize(); err == nil {
+		t.Error("unknown unit should fail")
+	}
+
+	m := f.GetMap("spider.downloader::headers")
+	if len(m) != 2 || m["Accept"] != "*/*" || m["User-Agent"] != "golang" {
+		t.Errorf("got %v", m)
+	}
+}
+
+func TestFileReader_Lookup(t *testing.T) {
+	f, name := openTemp(t, typesIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	if _, ok := f.Lookup("spider::missing"); ok {
+		t.Error("missing key should return false")
+	}
+	if _, ok := f.Lookup("spider"); ok {
+		t.Error("syntax error should return false")
+	}
+	if v, ok := f.Lookup("spider::timeout"); !ok || v.GetAsString() != "30s" {
+		t.Errorf("got %v", v)
+	}
+	if v := f.GetOr("spider::worker", "10").GetAsInt(); v != 10 {
+		t.Errorf("expect 10 got %d", v)
+	}
+	if v := f.GetOr("spider::timeout", "1s").GetAsString(); v != "30s" {
+		t.Errorf("expect 30s got %s", v)
+	}
+}
+
+func TestFileReader_UnmarshalTypes(t *testing.T) {
+	f, name := openTemp(t, typesIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	var cfg struct {
+		Timeout time.Duration     `ini:"timeout"`
+		Start   time.Time         `ini:"start"`
+		Seed    *url.URL          `ini:"seed"`
+		Proxy   net.IP            `ini:"proxy"`
+		Allow   *net.IPNet        `ini:"allow"`
+		Headers map[string]string `ini:"headers"`
+		Delay   time.Duration     `ini:"delay" default:"2s"`
+	}
+	if err := f.Unmarshal("spider", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Timeout != 30*time.Second || cfg.Seed == nil || cfg.Proxy == nil || cfg.Allow == nil ||
+		cfg.Start.IsZero() || len(cfg.Headers) != 2 || cfg.Delay != 2*time.Second {
+		t.Errorf("got %+v", cfg)
+	}
+
+	out := &FileReader{}
+	if err := out.Marshal("spider", &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if v := out.Get("spider::headers.Accept").GetAsString(); v != "text/html" {
+		t.Errorf("got %s", v)
+	}
+	if v := out.Get("spider::allow").GetAsString(); v != "192.168.1.0/24" {
+		t.Errorf("got %s", v)
+	}
+}
@@ -12,18 +12,59 @@ type conv struct {
 	data interface{}
 }
 
+// Conv 用于在包外声明回调函数的参数类型，例如Range
+type Conv = conv
+
 func (c *conv) GetAsString() string {
 	if c.data == nil {
 		return ""
 	}
-	return c.data.(string)
+	if d, ok := c.data.(string); ok {
+		return d
+	}
+	return fmt.Sprint(c.data)
 }
 
+// GetAsInt 无法转换时返回0，需要知道错误原因时使用GetAsInt64
 func (c *conv) GetAsInt() int {
-	if c.data == nil {
+	d, err := c.GetAsInt64()
+	if err != nil {
 		return 0
 	}
-	return c.data.(int)
+	return int(d)
+}
+
+func (c *conv) GetAsInt64() (int64, error) {
+	if c.data == nil {
+		return 0, errors.New("the data is nil")
+	}
+	switch d := c.data.(type) {
+	case int:
+		return int64(d), nil
+	case int64:
+		return d, nil
+	case string:
+		return strconv.ParseInt(strings.TrimSpace(d), 10, 64)
+	}
+	return 0, errors.New(fmt.Sprintf("can not conver %#v as type int64", c.data))
+}
+
+func (c *conv) GetAsUint64() (uint64, error) {
+	if c.data == nil {
+		return 0, errors.New("the data is nil")
+	}
+	switch d := c.data.(type) {
+	case int:
+		if d < 0 {
+			break
+		}
+		return uint64(d), nil
+	case uint64:
+		return d, nil
+	case string:
+		return strconv.ParseUint(strings.TrimSpace(d), 10, 64)
+	}
+	return 0, errors.New(fmt.Sprintf("can not conver %#v as type uint64", c.data))
 }
 
 func (c *conv) GetAsBool() (bool, error) {
@@ -99,8 +140,7 @@ func (c *conv) GetAsFloat64Slice() ([]float64, error) {
 		return arr, errors.New("the data is nil")
 	}
 	if d, ok := c.data.(string); ok {
-		d = strings.TrimSpace(d[1 : len(d)-1])
-		data := strings.Split(d, ",")
+		data := splitSlice(d)
 		for _, x := range data {
 			res, err := strconv.ParseFloat(x, 10)
 			if err != nil {
@@ -120,8 +160,7 @@ func (c *conv) GetAsIntSlice() ([]int, error) {
 		return arr, errors.New("the data is nil")
 	}
 	if d, ok := c.data.(string); ok {
-		d = strings.TrimSpace(d[1 : len(d)-1])
-		data := strings.Split(d, ",")
+		data := splitSlice(d)
 		for _, x := range data {
 			if res, err := strconv.ParseInt(x, 10, 32); err == nil {
 				arr = append(arr, int(res))
@@ -139,8 +178,7 @@ func (c *conv) GetAsStringSlice() ([]string, error) {
 		return arr, errors.New("the data is nil")
 	}
 	if d, ok := c.data.(string); ok {
-		d = strings.TrimSpace(d[1 : len(d)-1])
-		data := strings.Split(d, ",")
+		data := splitSlice(d)
 		for _, x := range data {
 			arr = append(arr, x)
 		}
@@ -149,6 +187,22 @@ func (c *conv) GetAsStringSlice() ([]string, error) {
 	return arr, nil
 }
 
+// splitSlice 拆分"[a,b,c]"形式的数组，空数组返回nil
+func splitSlice(d string) []string {
+	d = strings.TrimSpace(d)
+	if strings.HasPrefix(d, "[") && strings.HasSuffix(d, "]") {
+		d = strings.TrimSpace(d[1 : len(d)-1])
+	}
+	if d == "" {
+		return nil
+	}
+	data := strings.Split(d, ",")
+	for i := range data {
+		data[i] = strings.TrimSpace(data[i])
+	}
+	return data
+}
+
 type Value struct {
 	value map[string]interface{}
 	cap   uint64
@@ -170,3 +224,12 @@ func (v *Value) Put(key string, value string) {
 	v.value[key] = value
 	atomic.AddUint64(&v.cap, 1)
 }
+
+func (v *Value) Delete(key string) bool {
+	if _, ok := v.value[key]; !ok {
+		return false
+	}
+	delete(v.value, key)
+	atomic.AddUint64(&v.cap, ^uint64(0))
+	return true
+}
@@ -36,8 +36,8 @@ func TestConv_GetAsBool(t *testing.T) {
 
 }
 
-var strData = []string{"3.16", "0.0", "0", "-1", "123123", "y"}
-var strFake = []interface{}{1, false, "True", 0}
+var strData = []string{"3.16", "0.0", "0", "-1", "123123"}
+var strFake = []interface{}{1, false, "True", 0, "y"}
 
 func TestConv_GetAsFloat32(t *testing.T) {
 	for _, d := range strData {
@@ -60,11 +60,10 @@ func TestConv_GetAsFloat32(t *testing.T) {
 		c := conv{d}
 
 		res, err := c.GetAsFloat32()
-		if err != nil {
-			t.Error(err)
+		if err == nil {
+			t.Errorf("expect error converting %#v", d)
 		}
-		t.Log("convert ", d, "result", res)
-
+		t.Log("convert ", d, "result", res, err)
 	}
 }
 
@@ -89,10 +88,10 @@ func TestConv_GetAsFloat64(t *testing.T) {
 		c := conv{d}
 
 		res, err := c.GetAsFloat64()
-		if err != nil {
-			t.Log(err)
+		if err == nil {
+			t.Errorf("expect error converting %#v", d)
 		}
-		t.Log("convert ", d, "result", res)
+		t.Log("convert ", d, "result", res, err)
 	}
 }
 
@@ -105,24 +104,32 @@ func TestConv_GetAsString(t *testing.T) {
 }
 
 func TestValue_Get(t *testing.T) {
-	v := Value{}
-	v.Put("num",1)
-	v.Put("str","123")
-	v.Put("float",3.14)
-
-
-	t.Log(v.Get("num").GetAsInt())
-	t.Log(v.Get("str").GetAsString())
-	t.Log(v.Get("float").GetAsFloat32())
-
+	v := NewValue()
+	v.Put("num", "1")
+	v.Put("str", "123")
+	v.Put("float", "3.14")
 
+	if c, ok := v.Get("num"); !ok || c.GetAsInt() != 1 {
+		t.Error("num error")
+	}
+	if c, ok := v.Get("str"); !ok || c.GetAsString() != "123" {
+		t.Error("str error")
+	}
+	if c, ok := v.Get("float"); !ok {
+		t.Error("float error")
+	} else if f, err := c.GetAsFloat32(); err != nil || f != 3.14 {
+		t.Error("float error", err)
+	}
+	if _, ok := v.Get("none"); ok {
+		t.Error("expect no such key")
+	}
 }
 
 func TestValue_Put(t *testing.T) {
-	v := Value{}
-	v.Put("num",1)
-	v.Put("str","123")
-	v.Put("float",3.14)
+	v := NewValue()
+	v.Put("num", "1")
+	v.Put("str", "123")
+	v.Put("float", "3.14")
 
 	if v.cap != 3 {
 		t.Error("cap error")
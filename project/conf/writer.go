@@ -0,0 +1,331 @@
+package conf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func splitKey(str string) (space, key string, err error) {
+	data := strings.Split(str, "::")
+	if len(data) != 2 || data[1] == "" {
+		return "", "", errors.New("syntax error")
+	}
+	return data[0], data[1], nil
+}
+
+// section 返回段落第一次出现的位置，"name[i]"返回第i次出现的位置
+func (f *FileReader) section(name string) *section {
+	if base, idx, ok := tableIndex(name); ok {
+		if occ := f.occurrences(base); idx < len(occ) {
+			return occ[idx]
+		}
+		return nil
+	}
+	for _, s := range f.sections {
+		if s.name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func (f *FileReader) init() {
+	if f.data == nil {
+		f.data = make(map[string]Value)
+	}
+	if len(f.sections) == 0 {
+		f.sections = []*section{{value: *NewValue()}}
+	}
+	if f.origin == nil {
+		f.origin = make(map[string]Origin)
+	}
+}
+
+// AddSection 添加一个空的段落，已存在时不做任何修改，名字为空的段落为第一个段落之前的部分
+func (f *FileReader) AddSection(name string) error {
+	if f.root != nil {
+		return f.root.AddSection(f.scoped(name))
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.addSection(name)
+}
+
+func (f *FileReader) addSection(name string) error {
+	f.init()
+	name = f.name(strings.TrimSpace(name))
+	if f.section(name) != nil {
+		if _, ok := f.data[name]; !ok {
+			f.data[name] = *NewValue()
+		}
+		return nil
+	}
+	last := f.sections[len(f.sections)-1]
+	if n := len(last.lines); n > 0 && last.lines[n-1].kind != blankLine {
+		last.lines = append(last.lines, &line{kind: blankLine})
+	}
+	f.sections = append(f.sections, &section{
+		name:  name,
+		lines: []*line{{kind: sectionLine, text: "[" + name + "]"}},
+		value: *NewValue(),
+	})
+	f.data[name] = *NewValue()
+	return nil
+}
+
+// Set 使用"section::key"的形式设置值，段落或键不存在时自动创建，已存在的键保持原有位置
+func (f *FileReader) Set(str string, value string) error {
+	space, key, err := splitKey(str)
+	if err != nil {
+		return err
+	}
+	if f.root != nil {
+		return f.root.Set(f.scoped(space)+"::"+key, value)
+	}
+	if strings.ContainsAny(key, "=\n") {
+		return fmt.Errorf("illegal key %s", str)
+	}
+	space, key = f.name(space), f.name(key)
+	str = space + "::" + key
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	base, _, indexed := tableIndex(space)
+	if !indexed && f.isTable(space) {
+		// 没有指定下标时修改键所在的最后一次出现的段落，与Get的结果保持一致，都不包含该键时添加到最后一次出现的段落
+		occ := f.occurrences(space)
+		idx := len(occ) - 1
+		for i := len(occ) - 1; i >= 0; i-- {
+			if _, ok := occ[i].value.value[key]; ok {
+				idx = i
+				break
+			}
+		}
+		base, indexed = space, true
+		space = tableName(space, idx)
+		str = space + "::" + key
+	}
+	if indexed && f.section(space) == nil {
+		return fmt.Errorf("no such table %s", space)
+	}
+	if err := f.addSection(space); err != nil {
+		return err
+	}
+	s := f.section(space)
+	pos, found := 1, false
+	if space == "" {
+		for pos = len(s.lines); pos > 0 && s.lines[pos-1].kind == blankLine; pos-- {
+		}
+	}
+	for i, l := range s.lines {
+		if l.kind != keyLine {
+			continue
+		}
+		if l.key == key {
+			l.text, l.value = "", value
+			found = true
+			break
+		}
+		pos = i + 1
+	}
+	if !found {
+		s.lines = append(s.lines, nil)
+		copy(s.lines[pos+1:], s.lines[pos:])
+		s.lines[pos] = &line{kind: keyLine, key: key, value: value}
+	}
+
+	s.value.Put(key, value)
+	if indexed {
+		f.data[space] = s.value
+		f.remerge(base, key)
+	} else {
+		v := f.data[space]
+		v.Put(key, value)
+		f.data[space] = v
+	}
+	f.origin[str] = Origin{File: f.fileName}
+	return nil
+}
+
+// Delete 删除"section::key"对应的键
+func (f *FileReader) Delete(str string) error {
+	space, key, err := splitKey(str)
+	if err != nil {
+		return err
+	}
+	if f.root != nil {
+		return f.root.Delete(f.scoped(space) + "::" + key)
+	}
+	space, key = f.name(space), f.name(key)
+	str = space + "::" + key
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	base, idx, indexed := tableIndex(space)
+	var targets []*section
+	switch {
+	case indexed:
+		if s := f.section(space); s != nil {
+			targets = []*section{s}
+		}
+	case f.isTable(space):
+		// 没有指定下标时从所有出现的段落中删除
+		base, idx, indexed = space, -1, true
+		targets = f.occurrences(space)
+	default:
+		if s := f.section(space); s != nil {
+			targets = []*section{s}
+		}
+	}
+
+	found := false
+	for i, s := range targets {
+		for j, l := range s.lines {
+			if l.kind == keyLine && l.key == key {
+				s.lines = append(s.lines[:j], s.lines[j+1:]...)
+				s.value.Delete(key)
+				if indexed {
+					n := idx
+					if n < 0 {
+						n = i
+					}
+					f.data[tableName(base, n)] = s.value
+					delete(f.origin, tableName(base, n)+"::"+key)
+				}
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return fmt.Errorf("no such key %s", str)
+	}
+	if indexed {
+		f.remerge(base, key)
+		return nil
+	}
+	v := f.data[space]
+	v.Delete(key)
+	f.data[space] = v
+	delete(f.origin, str)
+	return nil
+}
+
+// DeleteSection 删除整个段落，包括段落内的注释，重复出现的段落会全部删除，"name[i]"只删除第i次出现的段落
+func (f *FileReader) DeleteSection(name string) error {
+	if f.root != nil {
+		return f.root.DeleteSection(f.scoped(name))
+	}
+	name = f.name(name)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if base, _, ok := tableIndex(name); ok {
+		s := f.section(name)
+		if s == nil {
+			return fmt.Errorf("no such section %s", name)
+		}
+		f.removeSection(s)
+		f.clearTables(base)
+		f.indexTables(base)
+		for key := range s.value.value {
+			f.remerge(base, key)
+		}
+		return nil
+	}
+
+	occ := f.occurrences(name)
+	if len(occ) == 0 || name == "" {
+		return fmt.Errorf("no such section %s", name)
+	}
+	for _, s := range occ {
+		f.removeSection(s)
+	}
+	f.clearTables(name)
+	delete(f.data, name)
+	for k := range f.origin {
+		if strings.HasPrefix(k, name+"::") {
+			delete(f.origin, k)
+		}
+	}
+	return nil
+}
+
+func (f *FileReader) removeSection(s *section) {
+	for i, sec := range f.sections {
+		if sec == s && i > 0 {
+			f.sections = append(f.sections[:i], f.sections[i+1:]...)
+			return
+		}
+	}
+}
+
+// WriteTo 按原文件的顺序输出，保留注释和空行
+func (f *FileReader) WriteTo(w io.Writer) (n int64, err error) {
+	if f.root != nil {
+		return f.root.WriteTo(w)
+	}
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	buf := bufio.NewWriter(w)
+	for _, s := range f.sections {
+		for _, l := range s.lines {
+			c, err := buf.WriteString(l.String() + "\n")
+			n += int64(c)
+			if err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, buf.Flush()
+}
+
+// Save 先写入同目录下的临时文件再重命名，保证写入是原子的，fileName为空时写回原文件，
+// 只能保存INI格式的内容，使用JSON、DotEnv等Loader解析的内容返回错误
+func (f *FileReader) Save(fileName string) (err error) {
+	if f.root != nil {
+		return f.root.Save(fileName)
+	}
+	if _, ok := f.loader.(iniLoader); f.loader != nil && !ok {
+		return fmt.Errorf("%s: only ini files can be saved, got %T", f.fileName, f.loader)
+	}
+	if fileName == "" {
+		fileName = f.fileName
+	}
+	if fileName == "" {
+		return errors.New("file name is empty")
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(fileName); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(fileName), "."+filepath.Base(fileName)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tmp.Close()
+			os.Remove(tmp.Name())
+		}
+	}()
+	if _, err = f.WriteTo(tmp); err != nil {
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		return err
+	}
+	if err = tmp.Chmod(mode); err != nil {
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), fileName)
+}
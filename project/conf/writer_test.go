@@ -0,0 +1,179 @@
+package conf
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const writerIni = `# crawler config
+
+[user]
+# login
+username = admin
+port = 80
+
+[administrator]
+username = administrator
+# cc
+`
+
+func openTemp(t *testing.T, content string) (*FileReader, string) {
+	dir, err := ioutil.TempDir("", "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "test.ini")
+	if err := ioutil.WriteFile(name, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	f := &FileReader{}
+	if err := f.Open(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Parser(); err != nil {
+		t.Fatal(err)
+	}
+	return f, name
+}
+
+func TestFileReader_WriteTo(t *testing.T) {
+	f, name := openTemp(t, writerIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	var buf bytes.Buffer
+	if _, err := f.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != writerIni {
+		t.Errorf("round trip changed the file:\n%s", buf.String())
+	}
+}
+
+func TestFileReader_Set(t *testing.T) {
+	f, name := openTemp(t, writerIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	if err := f.Set("user::port", "8080"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("user::server", "192.168.1.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Set("proxy::host", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("administrator::username"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Delete("administrator::password"); err == nil {
+		t.Error("delete a missing key should fail")
+	}
+	if err := f.Save(""); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := `# crawler config
+
+[user]
+# login
+username = admin
+port = 8080
+server = 192.168.1.1
+
+[administrator]
+# cc
+
+[proxy]
+host = 127.0.0.1
+`
+	if string(data) != expect {
+		t.Errorf("unexpected content:\n%s", data)
+	}
+	if v := f.Get("proxy::host").GetAsString(); v != "127.0.0.1" {
+		t.Errorf("got %s", v)
+	}
+}
+
+// 修改值之后保留行内注释
+func TestFileReader_SetInlineComment(t *testing.T) {
+	f, name := openTemp(t, "[user]\nport = 80 ; http port\nname = \"admin\"\t# 用户名\nhost = a\\\n  b # 续行\nempty = ; 空值\n")
+	defer os.RemoveAll(filepath.Dir(name))
+
+	for key, value := range map[string]string{"port": "8080", "name": "root", "host": "c", "empty": "x"} {
+		if err := f.Set("user::"+key, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.Save(""); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(name)
+	expect := "[user]\nport = 8080 ; http port\nname = root\t# 用户名\nhost = c # 续行\nempty = x ; 空值\n"
+	if string(data) != expect {
+		t.Errorf("unexpected content:\n%s", data)
+	}
+
+	f, name = openTemp(t, string(data))
+	defer os.RemoveAll(filepath.Dir(name))
+	if v := f.Get("user::port").GetAsString(); v != "8080" {
+		t.Errorf("got %s", v)
+	}
+	if v := f.Get("user::host").GetAsString(); v != "c" {
+		t.Errorf("got %s", v)
+	}
+}
+
+func TestFileReader_DeleteSection(t *testing.T) {
+	f, name := openTemp(t, writerIni)
+	defer os.RemoveAll(filepath.Dir(name))
+
+	if err := f.DeleteSection("user"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.DeleteSection("user"); err == nil {
+		t.Error("delete a missing section should fail")
+	}
+	var buf bytes.Buffer
+	f.WriteTo(&buf)
+	expect := "# crawler config\n\n[administrator]\nusername = administrator\n# cc\n"
+	if buf.String() != expect {
+		t.Errorf("unexpected content:\n%s", buf.String())
+	}
+}
+
+func TestFileReader_SaveFormat(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"spider.json": `{"db": {"password": "admin123"}}`,
+		"spider.ini":  "[db]\npassword = admin123\n",
+	})
+	defer os.RemoveAll(dir)
+
+	f, err := NewParser(filepath.Join(dir, "spider.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Set("db::password", "root")
+	if err := f.Save(""); err == nil {
+		t.Error("expect error when saving json")
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "spider.json"))
+	if string(data) != `{"db": {"password": "admin123"}}` {
+		t.Errorf("got %s", data)
+	}
+
+	f, err = NewParser(filepath.Join(dir, "spider.ini"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Set("db::password", "root")
+	if err := f.Save(""); err != nil {
+		t.Fatal(err)
+	}
+}
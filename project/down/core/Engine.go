@@ -1,7 +1,18 @@
 package core
 
 import (
+	"context"
+	"down/dedup"
+	"down/exception"
+	"down/ratelimit"
+	"down/robots"
 	"down/saver"
+	"errors"
+	"fmt"
+	"helper/logs"
+	"io"
+	"os"
+	"sync"
 	"time"
 )
 
@@ -14,72 +25,329 @@ import (
 */
 
 type Engine struct {
-	Scheduler   Scheduler        //调度器
-	WorkChanNum int              // 最大channel数量
-	ItemSave    chan interface{} // 解析出来的item
-	resp        chan Response    //请求结果
+	Scheduler   Scheduler               //调度器
+	WorkChanNum int                     // 最大channel数量
+	Saver       saver.Saver             // 保存解析出来的item
+	Filter      dedup.Filter            // 已经访问过的请求，为nil时不去重
+	Limiter     *ratelimit.Limiter      // 按host限速，为nil时不限速
+	Robots      *robots.Cache           // 遵守robots.txt，为nil时不检查
+	Retry       RetryPolicy             // 可重试错误的重试策略
+	FailedLog   *FailedLog              // 记录重试次数用完的请求，为nil时不记录
+	Errors      exception.DownloadError // 处理请求、解析和保存的错误，由Run启动和关闭，为nil时不处理
+	Report      io.Writer               // Run结束时写入JSON格式的错误报告，为nil时不写入
+	resp        chan result             //请求结果
+	items       chan item               // 等待Saver保存的item
+	running     *sync.WaitGroup         // 本次Run启动的worker以及等待重试的goroutine
 }
 
-func NewEngine() Engine {
+const (
+	// RobotsAgent 匹配robots.txt中user-agent分组使用的名称
+	RobotsAgent = "down"
+	// UserAgent NewGetRequest等函数创建的请求使用的User-Agent，包含RobotsAgent，网站可以据此在robots.txt中设置规则
+	UserAgent = "Mozilla/5.0 (compatible; " + RobotsAgent + "/1.0)"
+)
+
+// Result Run结束时的统计
+type Result struct {
+	Requests int64            // 成功完成的请求数
+	Failed   int64            // 失败的请求数
+	Skipped  int64            // 已经访问过或者被robots.txt禁止而被跳过的请求数
+	Retries  int64            // 重试的次数
+	Items    int64            // 保存的item数
+	Charsets map[string]int64 // 每种编码的页面数
+	Duration time.Duration    // 运行时间
+	Errors   []error          // 请求失败的原因，请求、解析和保存的错误为*exception.Error
+	Err      error            // context被取消时为ctx.Err()，正常结束时为nil
+}
+
+// item 解析出来的item以及解析的请求
+type item struct {
+	value   interface{}
+	request Request
+}
+
+// result worker处理完一个请求之后交给引擎的结果
+type result struct {
+	request    Request
+	response   Response
+	err        error
+	disallowed bool // 被robots.txt禁止，没有发送请求，err不为nil时表示robots.txt获取失败
+}
+
+// Option NewEngine的选项
+type Option func(*Engine)
+
+// WithScheduler 使用s代替默认的QueueScheduler
+func WithScheduler(s Scheduler) Option {
+	return func(e *Engine) {
+		e.Scheduler = s
+	}
+}
+
+func NewEngine(opts ...Option) Engine {
 	eng := Engine{
 		Scheduler:   &QueueScheduler{},
 		WorkChanNum: 10,
-		ItemSave:    saver.Save(),
-		resp:        make(chan Response,10),
+		Saver:       saver.NewJSONLines(os.Stdout),
+		Filter:      dedup.NewBloom(1<<20, 0.0001),
+		Limiter:     ratelimit.New(ratelimit.Options{Rate: 1, Burst: 1, Jitter: 500 * time.Millisecond}),
+		Retry:       DefaultRetryPolicy(),
+		Robots:      robots.NewCache(RobotsAgent, nil),
+		Errors:      exception.NewError(100, "engine"),
+	}
+	for _, opt := range opts {
+		opt(&eng)
 	}
 	return eng
 }
 
-func (e *Engine) Run(request Request) {
-
-	e.Scheduler.Start()
+// Run 提交种子请求并开始抓取，所有请求都处理完并且item都已保存，或者ctx被取消时返回，
+// 返回之前调用Saver.Flush，Saver由调用者关闭，返回之后可以再次调用Run
+func (e *Engine) Run(ctx context.Context, seeds ...Request) Result {
+	start := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	// 每次Run使用新的channel，避免读到上一次被取消时留下的结果
+	e.resp = make(chan result, e.WorkChanNum)
+	e.running = &sync.WaitGroup{}
+	if e.Saver == nil {
+		e.Saver = saver.Discard
+	}
+	if e.Errors != nil {
+		e.Errors.Start()
+	}
+	e.items = make(chan item, 100)
+	saved := make(chan Result)
+	go e.save(saved)
 
-	for i:=0;i<e.WorkChanNum；i++{
-		e.createWorker(e.Scheduler.WorkChan(), e.Scheduler)
+	e.Scheduler.Start(ctx)
+	for i := 0; i < e.WorkChanNum; i++ {
+		e.createWorker(ctx, e.Scheduler.WorkChan(), e.Scheduler)
 	}
 
-	e.Scheduler.Submit(request)
+	var res Result
+	pending := 0
+	for _, seed := range seeds {
+		if seed.Req == nil {
+			res.Failed++
+			res.Errors = append(res.Errors, errors.New("seed request is nil"))
+			continue
+		}
+		// 种子总是会被请求，同时记录下来避免再次从页面中提取到时重复请求
+		e.seen(seed)
+		e.Scheduler.Submit(seed)
+		pending++
+	}
 
-	e.Handler()
+	res.Err = e.handle(ctx, pending, &res)
+	// 停止调度器，等待worker以及重试的goroutine退出，之后调度器和Errors可以被下一次Run重新启动
+	cancel()
+	e.running.Wait()
+	close(e.items)
+	s := <-saved
+	res.Items = s.Items
+	res.Errors = append(res.Errors, s.Errors...)
+	if e.Errors != nil {
+		e.Errors.Close()
+		if e.Report != nil {
+			if err := e.Errors.WriteReport(e.Report); err != nil {
+				res.Errors = append(res.Errors, fmt.Errorf("write error report: %v", err))
+			}
+		}
+	}
+	res.Duration = time.Since(start)
+	return res
 }
 
-func (e *Engine) Handler() {
-	for {
-		res := <-e.resp
+// handle 处理worker返回的结果，pending为已经提交但是还没有返回结果的请求数，为0时表示抓取完成
+func (e *Engine) handle(ctx context.Context, pending int, res *Result) error {
+	for pending > 0 {
+		var r result
+		select {
+		case r = <-e.resp:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		pending--
+
+		if r.disallowed && r.err != nil {
+			// robots.txt获取失败时整个host暂时不能访问，作为请求失败处理，不重试
+			res.Failed++
+			res.Errors = append(res.Errors, e.sendError(exception.Fetch, r.request, r.err))
+			if e.FailedLog != nil {
+				if err := e.FailedLog.Record(r.request, r.err); err != nil {
+					res.Errors = append(res.Errors, fmt.Errorf("record failed request: %v", err))
+				}
+			}
+			continue
+		}
+		if r.disallowed {
+			logs.Info("robots.txt disallow %s", r.request.Req.URL)
+			res.Skipped++
+			continue
+		}
+		if r.err != nil {
+			if e.retry(ctx, r.request, r.err) {
+				res.Retries++
+				pending++
+				continue
+			}
+			res.Failed++
+			t := exception.Fetch
+			var parseErr *ParseError
+			if errors.As(r.err, &parseErr) {
+				t = exception.Parse
+			}
+			res.Errors = append(res.Errors, e.sendError(t, r.request, r.err))
+			if e.FailedLog != nil && Retryable(r.err) {
+				if err := e.FailedLog.Record(r.request, r.err); err != nil {
+					res.Errors = append(res.Errors, fmt.Errorf("record failed request: %v", err))
+				}
+			}
+			continue
+		}
+		res.Requests++
+		if cs := r.response.GetCharset(); cs != "" {
+			if res.Charsets == nil {
+				res.Charsets = make(map[string]int64)
+			}
+			res.Charsets[cs]++
+		}
 
-		for _, item := range res.GetItemQueue() {
-			if item != nil {
-				e.save(item)
+		for _, value := range r.response.GetItemQueue() {
+			if value == nil {
+				continue
+			}
+			select {
+			case e.items <- item{value: value, request: r.request}:
+			case <-ctx.Done():
+				return ctx.Err()
 			}
 		}
-		time.Sleep(time.Second)
 
-		for _, request := range res.GetRequestQueue() {
+		for _, request := range r.response.GetRequestQueue() {
+			request.Depth = r.request.Depth + 1
+			if e.seen(request) {
+				res.Skipped++
+				continue
+			}
 			e.Scheduler.Submit(request)
+			pending++
 		}
-		//if res.SizeOfRequestQueue() == 0 && res.SizeOfItemQueue() == 0 {
-		//	return
-		//}
 	}
+	return nil
 }
 
-func (e *Engine) save(item interface{}) {
+// retry 错误可以重试并且没有超过重试次数时，等待退避时间后重新提交请求
+func (e *Engine) retry(ctx context.Context, request Request, err error) bool {
+	if !Retryable(err) || request.Attempt+1 >= e.Retry.MaxAttempts {
+		return false
+	}
+	next, ok := request.retry()
+	if !ok {
+		return false
+	}
+	logs.Warn("retry %s attempt %d: %v", request.Req.URL, next.Attempt+1, err)
+	delay := e.Retry.Backoff(next.Attempt, err)
+	e.running.Add(1)
 	go func() {
-		e.ItemSave <- item
+		defer e.running.Done()
+		t := time.NewTimer(delay)
+		defer t.Stop()
+		select {
+		case <-t.C:
+			e.Scheduler.Submit(next)
+		case <-ctx.Done():
+		}
 	}()
+	return true
+}
+
+// sendError 为err加上分类和请求的信息并交给Errors处理，返回加上信息之后的错误
+func (e *Engine) sendError(t exception.ErrorType, request Request, err error) error {
+	ex := &exception.Error{Type: t, Err: err, Time: time.Now()}
+	if request.Req != nil {
+		ex.URL = request.Req.URL.String()
+		ex.Parser = ParserName(request.ParserFunc)
+		ex.Attempt = request.Attempt + 1
+	}
+	if e.Errors != nil {
+		e.Errors.SendError(ex)
+	}
+	return ex
 }
 
-func (e *Engine) createWorker(work chan Request, notify Notify) {
+// seen 请求是否已经访问过，无法计算去重键时当作没有访问过
+func (e *Engine) seen(request Request) bool {
+	if e.Filter == nil {
+		return false
+	}
+	key, err := dedup.Key(request.Req)
+	if err != nil {
+		return false
+	}
+	return e.Filter.Seen(key)
+}
+
+// save 在单独的goroutine中依次保存item，items关闭后刷新Saver并返回保存的数量和错误
+func (e *Engine) save(done chan<- Result) {
+	var res Result
+	for item := range e.items {
+		if err := e.Saver.Save(item.value); err != nil {
+			res.Errors = append(res.Errors, e.sendError(exception.Save, item.request, fmt.Errorf("save item: %v", err)))
+			continue
+		}
+		res.Items++
+	}
+	if err := e.Saver.Flush(); err != nil {
+		res.Errors = append(res.Errors, e.sendError(exception.Save, Request{}, fmt.Errorf("flush saver: %v", err)))
+	}
+	done <- res
+}
+
+func (e *Engine) createWorker(ctx context.Context, work chan Request, notify Notify) {
 	wo := NewParserWork()
+	e.running.Add(1)
 	go func(work chan Request) {
+		defer e.running.Done()
 		for {
 			notify.WorkReady(work)
-			request := <-work
-			Result, err := Worker(request, wo)
-			if err != nil {
-				continue
+			var request Request
+			select {
+			case request = <-work:
+			case <-ctx.Done():
+				return
+			}
+			request.Req = request.Req.WithContext(ctx)
+			host := request.Req.URL.Host
+			if e.Robots != nil {
+				allowed, delay, err := e.Robots.Check(ctx, request.Req.URL)
+				if e.Limiter != nil {
+					e.Limiter.SetDelay(host, delay)
+				}
+				if !allowed {
+					select {
+					case e.resp <- result{request: request, disallowed: true, err: err}:
+						continue
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+			if e.Limiter != nil {
+				if err := e.Limiter.Wait(ctx, host); err != nil {
+					return
+				}
+			}
+			response, err := Worker(request, wo)
+			if e.Limiter != nil {
+				e.Limiter.Feedback(host, StatusCode(err))
+			}
+			select {
+			case e.resp <- result{request: request, response: response, err: err}:
+			case <-ctx.Done():
+				return
 			}
-			e.resp <- Result
 		}
 	}(work)
 }
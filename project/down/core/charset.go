@@ -0,0 +1,53 @@
+package core
+
+import (
+	"bytes"
+	"golang.org/x/net/html/charset"
+	"golang.org/x/text/encoding"
+	"mime"
+	"strings"
+	"unicode/utf8"
+)
+
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// DecodeBody 把响应的内容转为UTF-8，依次根据BOM、contentType中的charset、<meta>声明以及内容判断编码，
+// 返回转换后的内容和编码名称，不是文本的内容原样返回，编码名称为空
+func DecodeBody(content []byte, contentType string) ([]byte, string, error) {
+	if !isText(contentType) {
+		return content, "", nil
+	}
+	enc, name, certain := charset.DetermineEncoding(content, contentType)
+	// 无法判断时默认为windows-1252，DetermineEncoding只检查前1024个字节，这里检查全部内容
+	if !certain && name == "windows-1252" && utf8.Valid(content) {
+		enc, name = encoding.Nop, "utf-8"
+	}
+	if name != "utf-8" {
+		decoded, err := enc.NewDecoder().Bytes(content)
+		if err != nil {
+			return content, name, err
+		}
+		content = decoded
+	}
+	// UTF-16的BOM解码之后也是UTF-8的BOM
+	return bytes.TrimPrefix(content, utf8BOM), name, nil
+}
+
+// isText 没有Content-Type时当作文本处理
+func isText(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	media, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return true
+	}
+	switch {
+	case strings.HasPrefix(media, "text/"),
+		strings.HasSuffix(media, "+xml"), strings.HasSuffix(media, "/xml"),
+		strings.HasSuffix(media, "+json"), strings.HasSuffix(media, "/json"),
+		media == "application/javascript", media == "application/x-www-form-urlencoded":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package core
+
+import (
+	"context"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func gbk(t *testing.T, s string) []byte {
+	b, err := simplifiedchinese.GBK.NewEncoder().Bytes([]byte(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func TestDecodeBody(t *testing.T) {
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes([]byte("<p>职位</p>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		content     []byte
+		contentType string
+		want        string
+		charset     string
+	}{
+		{"header", gbk(t, "<p>职位</p>"), "text/html; charset=gb2312", "<p>职位</p>", "gbk"},
+		{"meta", gbk(t, `<meta charset="gbk"><p>职位</p>`), "text/html", `<meta charset="gbk"><p>职位</p>`, "gbk"},
+		{"http-equiv", gbk(t, `<meta http-equiv="Content-Type" content="text/html; charset=gb2312"><p>职位</p>`), "",
+			`<meta http-equiv="Content-Type" content="text/html; charset=gb2312"><p>职位</p>`, "gbk"},
+		{"header wins", gbk(t, `<meta charset="utf-8"><p>职位</p>`), "text/html; charset=GBK", `<meta charset="utf-8"><p>职位</p>`, "gbk"},
+		{"utf-8 bom", append([]byte("\xef\xbb\xbf"), "<p>职位</p>"...), "text/html; charset=gbk", "<p>职位</p>", "utf-8"},
+		{"utf-16 bom", utf16, "text/html", "<p>职位</p>", "utf-16le"},
+		{"sniff utf-8", []byte("<p>职位</p>"), "text/html", "<p>职位</p>", "utf-8"},
+		{"ascii", []byte("<p>jobs</p>"), "", "<p>jobs</p>", "utf-8"},
+		{"json", []byte(`{"name":"职位"}`), "application/json", `{"name":"职位"}`, "utf-8"},
+		{"binary", []byte("\x89PNG\r\n"), "image/png", "\x89PNG\r\n", ""},
+	}
+	for _, tt := range tests {
+		got, charset, err := DecodeBody(tt.content, tt.contentType)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if string(got) != tt.want || charset != tt.charset {
+			t.Errorf("%s: got %q %s, want %q %s", tt.name, got, charset, tt.want, tt.charset)
+		}
+	}
+}
+
+func TestEngine_Charset(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/gbk":
+			w.Header().Set("Content-Type", "text/html; charset=gb2312")
+			w.Write(gbk(t, "<p>职位</p>"))
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<meta charset="utf-8"><p>职位</p>`))
+		}
+	}))
+	defer server.Close()
+
+	parser := func(content []byte) Response {
+		res := NewRequestResult()
+		res.AppendItem(string(content))
+		return res
+	}
+	eng, s := newTestEngine()
+	res := eng.Run(context.Background(), NewGetRequest(server.URL+"/gbk", parser), NewGetRequest(server.URL+"/utf8", parser))
+	if res.Requests != 2 || res.Charsets["gbk"] != 1 || res.Charsets["utf-8"] != 1 {
+		t.Errorf("got %+v", res)
+	}
+	for _, item := range s.items {
+		if item != "<p>职位</p>" && item != `<meta charset="utf-8"><p>职位</p>` {
+			t.Errorf("got %q", item)
+		}
+	}
+}
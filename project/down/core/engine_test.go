@@ -0,0 +1,271 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"down/dedup"
+	"down/exception"
+	"down/robots"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memorySaver struct {
+	items []interface{}
+}
+
+func (m *memorySaver) Save(item interface{}) error {
+	m.items = append(m.items, item)
+	return nil
+}
+
+func (m *memorySaver) Flush() error { return nil }
+func (m *memorySaver) Close() error { return nil }
+
+func newTestEngine() (*Engine, *memorySaver) {
+	s := &memorySaver{}
+	return &Engine{Scheduler: &QueueScheduler{}, WorkChanNum: 2, Saver: s, Filter: dedup.NewBloom(1000, 0.0001)}, s
+}
+
+func TestEngine_Run(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	var parser func([]byte) Response
+	parser = func(content []byte) Response {
+		res := NewRequestResult()
+		res.AppendItem(string(content))
+		switch string(content) {
+		case "/":
+			res.AppendRequest(NewGetRequest(server.URL+"/a", parser))
+			res.AppendRequest(NewGetRequest(server.URL+"/missing", parser))
+		case "/a":
+			// 互相链接的页面不会被重复请求
+			res.AppendRequest(NewGetRequest(server.URL, parser))
+			res.AppendRequest(NewGetRequest(server.URL+"/a#top", parser))
+		}
+		return res
+	}
+
+	eng, s := newTestEngine()
+	res := eng.Run(context.Background(), NewGetRequest(server.URL+"/", parser))
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	if res.Requests != 2 || res.Failed != 1 || res.Skipped != 2 || res.Items != 2 || len(res.Errors) != 1 {
+		t.Errorf("got %+v", res)
+	}
+	if len(s.items) != 2 {
+		t.Errorf("got %v", s.items)
+	}
+	if res.Duration <= 0 {
+		t.Error("expect duration")
+	}
+}
+
+func TestEngine_RunCancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	eng, _ := newTestEngine()
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	res := eng.Run(ctx, NewGetRequest(server.URL, func([]byte) Response { return NewRequestResult() }))
+	if res.Err != context.DeadlineExceeded {
+		t.Errorf("expect deadline exceeded got %v", res.Err)
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("run took %s", d)
+	}
+}
+
+func TestEngine_Robots(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/robots.txt":
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private\n")
+		case "/private":
+			t.Error("disallowed page requested")
+		default:
+			fmt.Fprint(w, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	var parser func([]byte) Response
+	parser = func(content []byte) Response {
+		res := NewRequestResult()
+		res.AppendItem(string(content))
+		if string(content) == "/" {
+			res.AppendRequest(NewGetRequest(server.URL+"/private", parser))
+			res.AppendRequest(NewGetRequest(server.URL+"/public", parser))
+		}
+		return res
+	}
+
+	eng, s := newTestEngine()
+	eng.Robots = robots.NewCache(RobotsAgent, nil)
+	res := eng.Run(context.Background(), NewGetRequest(server.URL+"/", parser))
+	// 被禁止的请求跳过，不算失败
+	if res.Requests != 2 || res.Skipped != 1 || res.Failed != 0 || len(s.items) != 2 {
+		t.Errorf("got %+v", res)
+	}
+}
+
+// robots.txt获取失败时整个host的请求都作为失败处理
+func TestEngine_RobotsUnavailable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/robots.txt" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		t.Errorf("unexpected request %s", r.URL)
+	}))
+	defer server.Close()
+
+	eng, _ := newTestEngine()
+	eng.Robots = robots.NewCache(RobotsAgent, nil)
+	eng.Errors = exception.NewError(10, "test")
+	res := eng.Run(context.Background(), NewGetRequest(server.URL+"/a", nil), NewGetRequest(server.URL+"/b", nil))
+	if res.Failed != 2 || res.Skipped != 0 || len(res.Errors) != 2 || eng.Errors.Count(exception.Fetch) != 2 {
+		t.Errorf("got %+v", res)
+	}
+	for _, err := range res.Errors {
+		if !strings.Contains(err.Error(), "robots.txt") {
+			t.Errorf("got %v", err)
+		}
+	}
+}
+
+// 请求的User-Agent包含匹配robots.txt使用的名称
+func TestNewRequest_UserAgent(t *testing.T) {
+	for _, r := range []Request{
+		NewGetRequest("http://a.com/", nil),
+		NewPostFormRequest("http://a.com/", nil, nil),
+	} {
+		if ua := r.Req.Header.Get("User-Agent"); !strings.Contains(ua, RobotsAgent) {
+			t.Errorf("got %q", ua)
+		}
+	}
+}
+
+type failingSaver struct{ memorySaver }
+
+func (f *failingSaver) Save(item interface{}) error {
+	if item == "/bad-item" {
+		return errors.New("cannot save")
+	}
+	return f.memorySaver.Save(item)
+}
+
+func TestEngine_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	var parser func([]byte) Response
+	parser = func(content []byte) Response {
+		res := NewRequestResult()
+		switch string(content) {
+		case "/":
+			for _, path := range []string{"/missing", "/panic", "/bad-item"} {
+				res.AppendRequest(NewGetRequest(server.URL+path, parser))
+			}
+		case "/panic":
+			panic("unexpected page")
+		}
+		res.AppendItem(string(content))
+		return res
+	}
+
+	var report bytes.Buffer
+	eng, _ := newTestEngine()
+	eng.Saver = &failingSaver{}
+	eng.Errors = exception.NewError(10, "test")
+	eng.Report = &report
+	res := eng.Run(context.Background(), NewGetRequest(server.URL+"/", parser))
+	if res.Requests != 2 || res.Failed != 2 || res.Items != 1 || len(res.Errors) != 3 {
+		t.Errorf("got %+v", res)
+	}
+	for _, typ := range []exception.ErrorType{exception.Fetch, exception.Parse, exception.Save} {
+		if n := eng.Errors.Count(typ); n != 1 {
+			t.Errorf("%s: got %d", typ, n)
+		}
+	}
+	for _, err := range res.Errors {
+		var ex *exception.Error
+		if !errors.As(err, &ex) || ex.URL == "" || ex.Attempt != 1 || !strings.Contains(ex.Parser, "TestEngine_Errors") {
+			t.Errorf("got %#v", err)
+		}
+	}
+
+	var r exception.Report
+	if err := json.Unmarshal(report.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	host := strings.TrimPrefix(server.URL, "http://")
+	if r.Total != 3 || r.Hosts[host][exception.Fetch] != 1 || r.Hosts[host][exception.Parse] != 1 || r.Hosts[host][exception.Save] != 1 {
+		t.Errorf("got %s", report.String())
+	}
+}
+
+// 同一个引擎可以多次运行，每次运行的错误单独统计，运行结束后不会留下goroutine
+func TestEngine_RunTwice(t *testing.T) {
+	before := runtime.NumGoroutine()
+	server := httptest.NewServer(http.NotFoundHandler())
+
+	for _, s := range []Scheduler{&QueueScheduler{}, &SimpleScheduler{}, &PriorityScheduler{}, &HostScheduler{}} {
+		eng := NewEngine(WithScheduler(s))
+		eng.Saver = &memorySaver{}
+		eng.Limiter, eng.Robots = nil, nil
+		for i := 0; i < 2; i++ {
+			var report bytes.Buffer
+			eng.Report = &report
+			res := eng.Run(context.Background(), NewGetRequest(server.URL+"/missing", nil))
+			if res.Requests != 0 || res.Failed != 1 || len(res.Errors) != 1 {
+				t.Errorf("%T run %d: got %+v", s, i, res)
+			}
+			if n := eng.Errors.Count(exception.Fetch); n != 1 {
+				t.Errorf("%T run %d: got %d", s, i, n)
+			}
+			var r exception.Report
+			if err := json.Unmarshal(report.Bytes(), &r); err != nil || r.Total != 1 {
+				t.Errorf("%T run %d: got %s %v", s, i, report.String(), err)
+			}
+		}
+	}
+	// 关闭服务器之后连接相关的goroutine也会退出，调度器的goroutine在Run返回后退出
+	server.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		buf := make([]byte, 1<<20)
+		t.Errorf("%d goroutines leaked\n%s", n-before, buf[:runtime.Stack(buf, true)])
+	}
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"runtime"
+	"sync"
+	"time"
+)
+
+// FailedRequest 重试次数用完的请求，Body按base64编码
+type FailedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Header  http.Header `json:"header,omitempty"`
+	Body    []byte      `json:"body,omitempty"`
+	Parser  string      `json:"parser"`
+	Attempt int         `json:"attempt"` // 一共请求的次数
+	Error   string      `json:"error"`
+	Time    time.Time   `json:"time"`
+}
+
+// FailedLog 以JSON Lines格式记录重试次数用完的请求，之后可以通过ReadFailed重新加载
+type FailedLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewFailedLog(w io.Writer) *FailedLog {
+	return &FailedLog{w: w}
+}
+
+// ParserName 解析函数的完整名称，例如"down/project.ParserJob"
+func ParserName(fn func([]byte) Response) string {
+	if fn == nil {
+		return ""
+	}
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return ""
+}
+
+func (l *FailedLog) Record(r Request, err error) error {
+	failed := FailedRequest{
+		Method:  r.Req.Method,
+		URL:     r.Req.URL.String(),
+		Header:  r.Req.Header,
+		Parser:  ParserName(r.ParserFunc),
+		Attempt: r.Attempt + 1,
+		Error:   err.Error(),
+		Time:    time.Now(),
+	}
+	if r.Req.GetBody != nil {
+		body, e := r.Req.GetBody()
+		if e != nil {
+			return e
+		}
+		failed.Body, e = ioutil.ReadAll(body)
+		body.Close()
+		if e != nil {
+			return e
+		}
+	}
+	data, e := json.Marshal(failed)
+	if e != nil {
+		return e
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, e = l.w.Write(append(data, '\n'))
+	return e
+}
+
+// ReadFailed 读取FailedLog记录的请求，parsers用于按名称还原ParserFunc，找不到解析函数时返回错误，
+// 还原的请求Attempt为0
+func ReadFailed(r io.Reader, parsers ...func([]byte) Response) ([]Request, error) {
+	byName := make(map[string]func([]byte) Response)
+	for _, p := range parsers {
+		byName[ParserName(p)] = p
+	}
+	var res []Request
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var failed FailedRequest
+		if err := json.Unmarshal(scanner.Bytes(), &failed); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		parser, ok := byName[failed.Parser]
+		if !ok && failed.Parser != "" {
+			return nil, fmt.Errorf("line %d: unknown parser %s", line, failed.Parser)
+		}
+		req, err := http.NewRequest(failed.Method, failed.URL, bytes.NewReader(failed.Body))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		if len(failed.Body) == 0 {
+			req.Body, req.GetBody, req.ContentLength = http.NoBody, nil, 0
+		}
+		for k, v := range failed.Header {
+			req.Header[k] = v
+		}
+		res = append(res, Request{Req: req, ParserFunc: parser})
+	}
+	return res, scanner.Err()
+}
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"context"
+	"helper/logs"
+	"strings"
+)
+
+// HostScheduler 每个host一个队列，按host轮流分配请求，同一个host的请求按提交的顺序分配，
+// 避免同时抓取多个网站时请求多的网站占满所有worker
+type HostScheduler struct {
+	requestChan chan Request
+	workChan    chan chan Request
+	ctx         context.Context
+}
+
+func (h *HostScheduler) WorkReady(r chan Request) {
+	select {
+	case h.workChan <- r:
+	case <-h.ctx.Done():
+	}
+}
+
+func (h *HostScheduler) Start(ctx context.Context) {
+	h.ctx = ctx
+	h.workChan = make(chan chan Request)
+	h.requestChan = make(chan Request)
+
+	go func(requestChan chan Request, workChan chan chan Request, done <-chan struct{}) {
+		var (
+			requestQueue = newHostQueue()
+			workQueue    []chan Request
+		)
+
+		for {
+			var (
+				activeRequest Request
+				activeWork    chan Request
+			)
+
+			if requestQueue.Len() > 0 && len(workQueue) > 0 {
+				activeRequest = requestQueue.Peek()
+				activeWork = workQueue[0]
+			}
+
+			select {
+			case req := <-requestChan:
+				requestQueue.Push(req)
+			case work := <-workChan:
+				workQueue = append(workQueue, work)
+			case activeWork <- activeRequest:
+				requestQueue.Pop()
+				workQueue = workQueue[1:]
+			case <-done:
+				return
+			}
+		}
+	}(h.requestChan, h.workChan, ctx.Done())
+}
+
+func (h *HostScheduler) Submit(request Request) {
+	if request.Req == nil {
+		logs.Info("request is nil")
+		return
+	}
+	select {
+	case h.requestChan <- request:
+	case <-h.ctx.Done():
+	}
+}
+
+func (h *HostScheduler) WorkChan() chan Request {
+	return make(chan Request)
+}
+
+// hostQueue 按host分组的请求队列，Peek和Pop轮流返回每个host最早提交的请求
+type hostQueue struct {
+	hosts  []string // 有等待请求的host，按第一次提交的顺序排列
+	queues map[string][]Request
+	next   int // 下一个分配的host在hosts中的位置
+	size   int
+}
+
+func newHostQueue() *hostQueue {
+	return &hostQueue{queues: make(map[string][]Request)}
+}
+
+func (q *hostQueue) Len() int {
+	return q.size
+}
+
+func (q *hostQueue) Push(r Request) {
+	host := strings.ToLower(r.Req.URL.Host)
+	if len(q.queues[host]) == 0 {
+		q.hosts = append(q.hosts, host)
+	}
+	q.queues[host] = append(q.queues[host], r)
+	q.size++
+}
+
+func (q *hostQueue) Peek() Request {
+	return q.queues[q.hosts[q.next]][0]
+}
+
+func (q *hostQueue) Pop() Request {
+	host := q.hosts[q.next]
+	queue := q.queues[host]
+	r := queue[0]
+	queue[0] = Request{}
+	q.size--
+	if len(queue) == 1 {
+		// 队列空了，删除host之后next已经指向下一个host
+		delete(q.queues, host)
+		q.hosts = append(q.hosts[:q.next], q.hosts[q.next+1:]...)
+	} else {
+		q.queues[host] = queue[1:]
+		q.next++
+	}
+	if q.next >= len(q.hosts) {
+		q.next = 0
+	}
+	return r
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHostScheduler(t *testing.T) {
+	s := &HostScheduler{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+	for _, u := range []string{
+		"http://big.com/1", "http://big.com/2", "http://big.com/3", "http://big.com/4",
+		"http://a.com/1", "http://BIG.com/5", "http://b.com/1", "http://a.com/2",
+	} {
+		s.Submit(NewGetRequest(u, nil))
+	}
+
+	expect := []string{
+		"http://big.com/1", "http://a.com/1", "http://b.com/1",
+		"http://big.com/2", "http://a.com/2",
+		"http://big.com/3", "http://big.com/4", "http://BIG.com/5",
+	}
+	work := s.WorkChan()
+	for i, u := range expect {
+		s.WorkReady(work)
+		if r := <-work; r.Req.URL.String() != u {
+			t.Errorf("%d: expect %s got %s", i, u, r.Req.URL)
+		}
+	}
+}
+
+func TestHostQueue(t *testing.T) {
+	q := newHostQueue()
+	push := func(urls ...string) {
+		for _, u := range urls {
+			q.Push(NewGetRequest(u, nil))
+		}
+	}
+	pop := func() string {
+		if q.Peek().Req != q.Pop().Req {
+			t.Fatal("Peek and Pop differ")
+		}
+		return q.Peek().Req.URL.String()
+	}
+
+	push("http://a.com/1", "http://a.com/2", "http://b.com/1")
+	if next := pop(); next != "http://b.com/1" {
+		t.Errorf("got %s", next)
+	}
+	// 新加入的host排在最后，b.com的队列空了之后轮到c.com，然后回到a.com
+	push("http://c.com/1")
+	if next := pop(); next != "http://c.com/1" {
+		t.Errorf("got %s", next)
+	}
+	if next := pop(); next != "http://a.com/2" {
+		t.Errorf("got %s", next)
+	}
+	if q.Len() != 1 {
+		t.Errorf("got %d", q.Len())
+	}
+	q.Pop()
+	if q.Len() != 0 || len(q.hosts) != 0 || len(q.queues) != 0 {
+		t.Errorf("got %+v", q)
+	}
+}
+
+// 所有调度器都可以用于引擎
+func TestEngine_Schedulers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	var parser func([]byte) Response
+	parser = func(content []byte) Response {
+		res := NewRequestResult()
+		res.AppendItem(string(content))
+		if string(content) == "/" {
+			for i := 0; i < 5; i++ {
+				res.AppendRequest(NewGetRequest(fmt.Sprintf("%s/%d", server.URL, i), parser))
+			}
+		}
+		return res
+	}
+
+	for _, s := range []Scheduler{&QueueScheduler{}, &SimpleScheduler{}, &PriorityScheduler{}, &HostScheduler{}} {
+		eng := NewEngine(WithScheduler(s))
+		eng.WorkChanNum = 3
+		eng.Saver = &memorySaver{}
+		eng.Limiter, eng.Robots = nil, nil
+		res := eng.Run(context.Background(), NewGetRequest(server.URL+"/", parser))
+		if res.Err != nil || res.Requests != 6 || res.Items != 6 || res.Failed != 0 {
+			t.Errorf("%T: got %+v", s, res)
+		}
+	}
+}
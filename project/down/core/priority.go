@@ -0,0 +1,110 @@
+package core
+
+import (
+	"container/heap"
+	"context"
+	"helper/logs"
+)
+
+// PriorityScheduler 按Request.Priority从高到低分配请求，优先级相同时先分配Depth小的，
+// 再按提交的顺序分配
+type PriorityScheduler struct {
+	requestChan chan Request
+	workChan    chan chan Request
+	ctx         context.Context
+}
+
+func (p *PriorityScheduler) WorkReady(r chan Request) {
+	select {
+	case p.workChan <- r:
+	case <-p.ctx.Done():
+	}
+}
+
+func (p *PriorityScheduler) Start(ctx context.Context) {
+	p.ctx = ctx
+	// 不使用缓冲，Submit返回时请求已经进入队列，保证提交的顺序
+	p.workChan = make(chan chan Request)
+	p.requestChan = make(chan Request)
+
+	go func(requestChan chan Request, workChan chan chan Request, done <-chan struct{}) {
+		var (
+			requestQueue requestHeap
+			workQueue    []chan Request
+			seq          int64
+		)
+
+		for {
+			var (
+				activeRequest Request
+				activeWork    chan Request
+			)
+
+			if len(requestQueue) > 0 && len(workQueue) > 0 {
+				activeRequest = requestQueue[0].Request
+				activeWork = workQueue[0]
+			}
+
+			select {
+			case req := <-requestChan:
+				heap.Push(&requestQueue, queued{Request: req, seq: seq})
+				seq++
+			case work := <-workChan:
+				workQueue = append(workQueue, work)
+			case activeWork <- activeRequest:
+				heap.Pop(&requestQueue)
+				workQueue = workQueue[1:]
+			case <-done:
+				return
+			}
+		}
+	}(p.requestChan, p.workChan, ctx.Done())
+}
+
+func (p *PriorityScheduler) Submit(request Request) {
+	if request.Req == nil {
+		logs.Info("request is nil")
+		return
+	}
+	select {
+	case p.requestChan <- request:
+	case <-p.ctx.Done():
+	}
+}
+
+func (p *PriorityScheduler) WorkChan() chan Request {
+	return make(chan Request)
+}
+
+type queued struct {
+	Request
+	seq int64 // 提交的顺序
+}
+
+// requestHeap 实现heap.Interface，堆顶是最先分配的请求
+type requestHeap []queued
+
+func (h requestHeap) Len() int { return len(h) }
+
+func (h requestHeap) Less(i, j int) bool {
+	a, b := h[i], h[j]
+	if a.Priority != b.Priority {
+		return a.Priority > b.Priority
+	}
+	if a.Depth != b.Depth {
+		return a.Depth < b.Depth
+	}
+	return a.seq < b.seq
+}
+
+func (h requestHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *requestHeap) Push(x interface{}) { *h = append(*h, x.(queued)) }
+
+func (h *requestHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	old[len(old)-1] = queued{}
+	*h = old[:len(old)-1]
+	return x
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"container/heap"
+	"context"
+	"testing"
+)
+
+func TestPriorityScheduler(t *testing.T) {
+	s := &PriorityScheduler{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s.Start(ctx)
+
+	newRequest := func(path string, priority, depth int) Request {
+		r := NewGetRequest("http://example.com"+path, nil)
+		r.Priority, r.Depth = priority, depth
+		return r
+	}
+	for _, r := range []Request{
+		newRequest("/list/2", 0, 1),
+		newRequest("/job/1", 1, 2),
+		newRequest("/list/1", 0, 0),
+		newRequest("/job/2", 1, 2),
+		newRequest("/urgent", 5, 3),
+		newRequest("/list/3", 0, 1),
+	} {
+		s.Submit(r)
+	}
+	s.Submit(Request{})
+
+	expect := []string{"/urgent", "/job/1", "/job/2", "/list/1", "/list/2", "/list/3"}
+	work := s.WorkChan()
+	for i, path := range expect {
+		s.WorkReady(work)
+		r := <-work
+		if r.Req.URL.Path != path {
+			t.Errorf("%d: expect %s got %s", i, path, r.Req.URL.Path)
+		}
+	}
+}
+
+func TestRequestHeap(t *testing.T) {
+	var h requestHeap
+	for i := 0; i < 100; i++ {
+		heap.Push(&h, queued{Request: Request{Priority: i % 3}, seq: int64(i)})
+	}
+	last := queued{Request: Request{Priority: 3}}
+	for h.Len() > 0 {
+		q := heap.Pop(&h).(queued)
+		if q.Priority > last.Priority || q.Priority == last.Priority && q.seq < last.seq {
+			t.Fatalf("%+v after %+v", q, last)
+		}
+		last = q
+	}
+}
@@ -1,22 +1,31 @@
 package core
 
-import "helper/logs"
+import (
+	"context"
+	"helper/logs"
+)
 
 type QueueScheduler struct {
 	requestChan chan Request
 	workChan    chan chan Request
 	Active      bool
+	ctx         context.Context
 }
 
 func (q *QueueScheduler) WorkReady(r chan Request) {
-	q.workChan <- r
+	select {
+	case q.workChan <- r:
+	case <-q.ctx.Done():
+	}
 }
 
-func (q *QueueScheduler) Start() {
+func (q *QueueScheduler) Start(ctx context.Context) {
+	q.ctx = ctx
 	q.workChan = make(chan chan Request,10)
 	q.requestChan = make(chan Request,10)
 
-	go func() {
+	// ctx被取消时退出，使用本次Start创建的channel，再次Start时之前的goroutine不会读到新的channel
+	go func(requestChan chan Request, workChan chan chan Request, done <-chan struct{}) {
 		var (
 			requestQueue []Request
 			workQueue    []chan Request
@@ -34,18 +43,20 @@ func (q *QueueScheduler) Start() {
 			}
 
 			select {
-			case req := <-q.requestChan:
+			case req := <-requestChan:
 				requestQueue = append(requestQueue, req)
-			case work := <-q.workChan:
+			case work := <-workChan:
 				workQueue = append(workQueue, work)
 			case activeWork <- activeRequest:
 				requestQueue = requestQueue[1:]
 				workQueue = workQueue[1:]
+			case <-done:
+				return
 			}
 
 		}
 
-	}()
+	}(q.requestChan, q.workChan, ctx.Done())
 }
 
 func (q *QueueScheduler) Submit(request Request) {
@@ -53,7 +64,10 @@ func (q *QueueScheduler) Submit(request Request) {
 		logs.Info("request is nil")
 		return
 	}
-	q.requestChan <- request
+	select {
+	case q.requestChan <- request:
+	case <-q.ctx.Done():
+	}
 }
 
 func (q *QueueScheduler) WorkChan() chan Request {
@@ -0,0 +1,103 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy 可重试的错误按指数退避重新请求，MaxAttempts包括第一次请求，小于等于1时不重试
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration // 第一次重试前等待的时间，之后每次翻倍
+	MaxDelay    time.Duration // 退避时间的上限，Retry-After不受限制
+	Jitter      float64       // 0~1，实际等待时间在[delay*(1-Jitter), delay]之间随机
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{MaxAttempts: 3, BaseDelay: time.Second, MaxDelay: 30 * time.Second, Jitter: 0.5}
+}
+
+var (
+	randMu  sync.Mutex
+	jitters = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+// Backoff 第attempt次失败之后需要等待的时间，响应中带有Retry-After并且更长时使用Retry-After
+func (p RetryPolicy) Backoff(attempt int, err error) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	if p.Jitter > 0 && delay > 0 {
+		randMu.Lock()
+		delay -= time.Duration(jitters.Float64() * p.Jitter * float64(delay))
+		randMu.Unlock()
+	}
+	var status *StatusError
+	if errors.As(err, &status) && status.RetryAfter > delay {
+		delay = status.RetryAfter
+	}
+	return delay
+}
+
+// Retryable 超时、连接被重置、5xx、429以及408可以重试，其他错误都是永久性的
+func Retryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var status *StatusError
+	if errors.As(err, &status) {
+		return status.Code == http.StatusTooManyRequests || status.Code == http.StatusRequestTimeout ||
+			status.Code >= 500 && status.Code != http.StatusNotImplemented
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF)
+}
+
+// parseRetryAfter 解析秒数或者HTTP日期形式的Retry-After
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// retry 返回重新请求使用的Request，请求体不能重新读取时返回false
+func (r Request) retry() (Request, bool) {
+	next := r
+	next.Attempt++
+	if r.Req.Body != nil && r.Req.Body != http.NoBody {
+		if r.Req.GetBody == nil {
+			return r, false
+		}
+		body, err := r.Req.GetBody()
+		if err != nil {
+			return r, false
+		}
+		next.Req = r.Req.Clone(r.Req.Context())
+		next.Req.Body = body
+	}
+	return next, true
+}
@@ -0,0 +1,183 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{nil, false},
+		{&StatusError{Code: 503}, true},
+		{&StatusError{Code: 500}, true},
+		{&StatusError{Code: 429}, true},
+		{&StatusError{Code: 408}, true},
+		{&StatusError{Code: 501}, false},
+		{&StatusError{Code: 404}, false},
+		{fmt.Errorf("get: %w", &StatusError{Code: 502}), true},
+		{timeoutError{}, true},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), true},
+		{io.ErrUnexpectedEOF, true},
+		{context.Canceled, false},
+		{errors.New("parse error"), false},
+	}
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	p := RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	for attempt, want := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 3: 4 * time.Second, 4: 5 * time.Second, 10: 5 * time.Second} {
+		if got := p.Backoff(attempt, nil); got != want {
+			t.Errorf("attempt %d: got %s, want %s", attempt, got, want)
+		}
+	}
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		if got := p.Backoff(2, nil); got < time.Second || got > 2*time.Second {
+			t.Fatalf("got %s", got)
+		}
+	}
+
+	// Retry-After比退避时间长时使用Retry-After
+	err := &StatusError{Code: 429, RetryAfter: time.Minute}
+	if got := p.Backoff(1, err); got != time.Minute {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"soon":                          0,
+		"Wed, 01 Jan 2020 00:00:30 GMT": 30 * time.Second,
+		"Tue, 31 Dec 2019 23:00:00 GMT": 0,
+	}
+	for v, want := range tests {
+		if got := parseRetryAfter(v, now); got != want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", v, got, want)
+		}
+	}
+}
+
+func TestFailedLog(t *testing.T) {
+	parser := func([]byte) Response { return NewRequestResult() }
+	req, _ := http.NewRequest("POST", "http://example.com/search", strings.NewReader("q=go"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var buf bytes.Buffer
+	log := NewFailedLog(&buf)
+	if err := log.Record(Request{Req: req, ParserFunc: parser, Attempt: 2}, &StatusError{Code: 503}); err != nil {
+		t.Fatal(err)
+	}
+	if err := log.Record(NewGetRequest("http://example.com/", nil), io.ErrUnexpectedEOF); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"attempt":3`) {
+		t.Errorf("got %s", buf.String())
+	}
+
+	if _, err := ReadFailed(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Error("expect unknown parser error")
+	}
+	requests, err := ReadFailed(&buf, parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Fatalf("got %d requests", len(requests))
+	}
+	r := requests[0]
+	if r.Req.Method != "POST" || r.Req.URL.String() != "http://example.com/search" || r.ParserFunc == nil || r.Attempt != 0 {
+		t.Errorf("got %+v", r)
+	}
+	if r.Req.Header.Get("Content-Type") != "application/x-www-form-urlencoded" {
+		t.Errorf("got header %v", r.Req.Header)
+	}
+	body, _ := ioutil.ReadAll(r.Req.Body)
+	if string(body) != "q=go" {
+		t.Errorf("got body %q", body)
+	}
+	if requests[1].ParserFunc != nil || requests[1].Req.Body != http.NoBody {
+		t.Errorf("got %+v", requests[1])
+	}
+}
+
+func TestEngine_Retry(t *testing.T) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/flaky":
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+		case "/down":
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		case "/missing":
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	parser := func(content []byte) Response {
+		res := NewRequestResult()
+		res.AppendItem(string(content))
+		return res
+	}
+	var failed bytes.Buffer
+	eng, s := newTestEngine()
+	eng.Retry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	eng.FailedLog = NewFailedLog(&failed)
+	res := eng.Run(context.Background(),
+		NewGetRequest(server.URL+"/flaky", parser),
+		NewGetRequest(server.URL+"/down", parser),
+		NewGetRequest(server.URL+"/missing", parser),
+	)
+	if res.Err != nil {
+		t.Fatal(res.Err)
+	}
+	// flaky重试两次后成功，down重试两次后失败，missing不重试
+	if res.Requests != 1 || res.Failed != 2 || res.Retries != 4 || len(s.items) != 1 {
+		t.Errorf("got %+v", res)
+	}
+
+	// 只有重试次数用完的请求会被记录
+	requests, err := ReadFailed(&failed, parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 || requests[0].Req.URL.Path != "/down" {
+		t.Errorf("got %v", requests)
+	}
+}
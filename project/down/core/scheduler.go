@@ -1,12 +1,22 @@
 package core
 
+import "context"
+
 type Notify interface {
 	WorkReady(chan Request)
 }
 
 type Scheduler interface {
 	Notify
-	Start()
+	// Start 开始调度，ctx被取消时停止，之后Submit和WorkReady直接返回，队列中剩余的请求被丢弃
+	Start(ctx context.Context)
 	Submit(request Request)
 	WorkChan() chan Request
 }
+
+var (
+	_ Scheduler = (*QueueScheduler)(nil)
+	_ Scheduler = (*SimpleScheduler)(nil)
+	_ Scheduler = (*PriorityScheduler)(nil)
+	_ Scheduler = (*HostScheduler)(nil)
+)
@@ -1,26 +1,42 @@
 package core
 
+import (
+	"context"
+	"helper/logs"
+)
+
+// SimpleScheduler 所有worker共享一个channel，每个请求由单独的goroutine提交，不保证顺序
 type SimpleScheduler struct {
 	workerChan chan Request
+	ctx        context.Context
 }
 
-func (s *SimpleScheduler) WorkerChan() chan Request {
+func (s *SimpleScheduler) WorkChan() chan Request {
 	return s.workerChan
 }
 
-func (s *SimpleScheduler) WorkerReady(chan Request) {
+// WorkReady worker直接从共享的channel中接收请求，不需要通知
+func (s *SimpleScheduler) WorkReady(chan Request) {
 }
 
-func (s *SimpleScheduler) Run() {
+func (s *SimpleScheduler) Start(ctx context.Context) {
+	s.ctx = ctx
 	s.workerChan = make(chan Request)
 }
 
 func (s *SimpleScheduler) Submit(r Request) {
+	if r.Req == nil {
+		logs.Info("request is nil")
+		return
+	}
 	//调度器将待完成的任务送到workerChan队列
 	// s.workerChan <- r 这行代码会造成死锁，不能保证每提交都会有相应的goroutine接收
 
-	// 开启另一个goroutine来提交任务
-	go func() {
-		s.workerChan <- r
-	}()
+	// 开启另一个goroutine来提交任务，ctx被取消时放弃
+	go func(workerChan chan Request, done <-chan struct{}) {
+		select {
+		case workerChan <- r:
+		case <-done:
+		}
+	}(s.workerChan, s.ctx.Done())
 }
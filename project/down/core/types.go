@@ -11,11 +11,14 @@ import (
 type Request struct {
 	Req        *http.Request
 	ParserFunc func([]byte) Response
+	Attempt    int // 已经失败的次数
+	Priority   int // 优先级，越大越先处理，只有PriorityScheduler使用
+	Depth      int // 从种子请求开始的层数，由引擎设置
 }
 
 func NewGetRequest(url string, ParserFunc func([]byte) Response) Request {
 	req, err := http.NewRequest("GET", url, nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Macintosh; Intel …) Gecko/20100101 Firefox/65.0")
+	req.Header.Set("User-Agent", UserAgent)
 	if err != nil {
 		logs.Error(err)
 	}
@@ -29,6 +32,7 @@ func NewPostFormRequest(url string, data url.Values, ParserFunc func([]byte) Res
 func NewPostRequest(url string, reader io.Reader, contentType string, ParserFunc func([]byte) Response) Request {
 	req, err := http.NewRequest("POST", url, reader)
 	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", UserAgent)
 	if err != nil {
 		logs.Error(err)
 	}
@@ -36,8 +40,9 @@ func NewPostRequest(url string, reader io.Reader, contentType string, ParserFunc
 }
 
 type Response struct {
-	req  []Request
-	item []interface{}
+	req     []Request
+	item    []interface{}
+	charset string // 响应内容原来的编码，由Worker设置
 }
 
 func (r *Response) GetRequestQueue() []Request {
@@ -47,6 +52,11 @@ func (r *Response) GetItemQueue() []interface{} {
 	return r.item
 }
 
+// GetCharset 响应内容原来的编码，解析函数得到的内容已经转为UTF-8
+func (r *Response) GetCharset() string {
+	return r.charset
+}
+
 func (r *Response) SizeOfRequestQueue() int {
 	return len(r.GetRequestQueue())
 }
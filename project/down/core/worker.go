@@ -12,29 +12,72 @@ import (
 )
 
 type Work interface {
-	StartWork(req *http.Request) (res []byte, err error)
+	// StartWork 返回转为UTF-8的响应内容以及原来的编码
+	StartWork(req *http.Request) (res []byte, charset string, err error)
 }
 
 func Worker(r Request, work Work) (Response, error) {
-	res, err := work.StartWork(r.Req)
+	res, charset, err := work.StartWork(r.Req)
 	if err != nil || r.ParserFunc == nil {
-		return Response{}, err
+		return Response{charset: charset}, err
 	}
-	return r.ParserFunc(res), nil
+	resp, err := parse(r.ParserFunc, res)
+	resp.charset = charset
+	return resp, err
+}
+
+// ParseError 解析函数panic
+type ParseError struct {
+	Value interface{} // recover得到的值
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("parser panic: %v", e.Value)
+}
+
+// parse 调用解析函数，panic时返回*ParseError
+func parse(fn func([]byte) Response, content []byte) (res Response, err error) {
+	defer func() {
+		if v := recover(); v != nil {
+			err = &ParseError{Value: v}
+		}
+	}()
+	return fn(content), nil
 }
 
 type ParseWork struct {
-	client  *http.Client
-	timeSec time.Duration
+	client *http.Client
 }
 
 func NewParserWork() *ParseWork {
 	p := &ParseWork{}
 	p.client = GenHttpClient()
-	p.timeSec = 2
 	return p
 }
 
+// StatusError 响应的状态码不是200
+type StatusError struct {
+	Code       int
+	URL        string
+	RetryAfter time.Duration // 响应头中的Retry-After，没有时为0
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unsupported status code %d (requestURL: %s)", e.Code, e.URL)
+}
+
+// StatusCode 返回err中的状态码，err为nil时返回200，没有状态码时返回0
+func StatusCode(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	var e *StatusError
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 0
+}
+
 func GenHttpClient() *http.Client {
 	return &http.Client{
 		Transport: &http.Transport{
@@ -52,30 +95,29 @@ func GenHttpClient() *http.Client {
 	}
 }
 
-func (t *ParseWork) StartWork(req *http.Request) (res []byte, err error) {
+func (t *ParseWork) StartWork(req *http.Request) (res []byte, charset string, err error) {
 	if req == nil {
 		return
 	}
-	time.After(time.Second * t.timeSec)
 	logs.Info(req.URL.String())
 	resp, err := t.client.Do(req)
 	if err != nil {
 		return
 	}
+	defer func() {
+		if e := resp.Body.Close(); err == nil {
+			err = e
+		}
+	}()
 	err = ResponseCheck(resp)
 	if err != nil {
 		return
 	}
-	defer func() {
-		err = resp.Body.Close()
-	}()
-
-	if resp.StatusCode != http.StatusOK {
-		err = errors.New(fmt.Sprintf("Response code not ok code<%d>", resp.StatusCode))
+	res, err = ioutil.ReadAll(bufio.NewReader(resp.Body))
+	if err != nil {
 		return
 	}
-	res, err = ioutil.ReadAll(bufio.NewReader(resp.Body))
-
+	res, charset, err = DecodeBody(res, resp.Header.Get("Content-Type"))
 	return
 }
 
@@ -89,7 +131,11 @@ func ResponseCheck(httpResp *http.Response) error {
 	}
 	reqURL := httpReq.URL
 	if httpResp.StatusCode != 200 {
-		return errors.New(fmt.Sprintf("unsupported status code %d (requestURL: %s)", httpResp.StatusCode, reqURL))
+		return &StatusError{
+			Code:       httpResp.StatusCode,
+			URL:        reqURL.String(),
+			RetryAfter: parseRetryAfter(httpResp.Header.Get("Retry-After"), time.Now()),
+		}
 	}
 	httpRespBody := httpResp.Body
 	if httpRespBody == nil {
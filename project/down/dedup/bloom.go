@@ -0,0 +1,146 @@
+package dedup
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"hash/fnv"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const bloomMagic = "BLM1"
+
+// Bloom 布隆过滤器，内存占用固定，存在一定的误判率，误判时会把没有访问过的请求当作已经访问过
+type Bloom struct {
+	mu    sync.Mutex
+	bits  []uint64
+	m     uint64 // 位数
+	k     uint64 // 哈希函数个数
+	count uint64 // 已经记录的key数量
+}
+
+// NewBloom 根据预计的key数量n和误判率p创建布隆过滤器
+func NewBloom(n uint64, p float64) *Bloom {
+	if n == 0 {
+		n = 1
+	}
+	if p <= 0 || p >= 1 {
+		p = 0.0001
+	}
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k := uint64(math.Ceil(float64(m) / float64(n) * math.Ln2))
+	if k == 0 {
+		k = 1
+	}
+	return &Bloom{bits: make([]uint64, (m+63)/64), m: m, k: k}
+}
+
+// hashes 使用两个FNV哈希组合出k个位置
+func (b *Bloom) hashes(key string) (uint64, uint64) {
+	h1 := fnv.New64a()
+	io.WriteString(h1, key)
+	h2 := fnv.New64()
+	io.WriteString(h2, key)
+	return h1.Sum64(), h2.Sum64() | 1
+}
+
+func (b *Bloom) Seen(key string) bool {
+	h1, h2 := b.hashes(key)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	seen := true
+	for i := uint64(0); i < b.k; i++ {
+		pos := (h1 + i*h2) % b.m
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			seen = false
+			b.bits[pos/64] |= 1 << (pos % 64)
+		}
+	}
+	if !seen {
+		b.count++
+	}
+	return seen
+}
+
+// Count 已经记录的key数量
+func (b *Bloom) Count() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.count
+}
+
+func (b *Bloom) WriteTo(w io.Writer) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	bw := bufio.NewWriter(w)
+	bw.WriteString(bloomMagic)
+	for _, v := range []uint64{b.m, b.k, b.count} {
+		binary.Write(bw, binary.LittleEndian, v)
+	}
+	binary.Write(bw, binary.LittleEndian, b.bits)
+	return int64(len(bloomMagic) + 24 + 8*len(b.bits)), bw.Flush()
+}
+
+// ReadBloom 读取WriteTo写入的布隆过滤器
+func ReadBloom(r io.Reader) (*Bloom, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(bloomMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != bloomMagic {
+		return nil, errors.New("not a bloom filter file")
+	}
+	var head [3]uint64
+	if err := binary.Read(br, binary.LittleEndian, &head); err != nil {
+		return nil, err
+	}
+	b := &Bloom{m: head[0], k: head[1], count: head[2]}
+	if b.m == 0 || b.k == 0 || b.m > 1<<40 {
+		return nil, errors.New("invalid bloom filter header")
+	}
+	b.bits = make([]uint64, (b.m+63)/64)
+	if err := binary.Read(br, binary.LittleEndian, b.bits); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// OpenBloom 从path中加载布隆过滤器，文件不存在时按n和p创建新的
+func OpenBloom(path string, n uint64, p float64) (*Bloom, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return NewBloom(n, p), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadBloom(f)
+}
+
+// Save 先写入临时文件再重命名，保证文件不会只写入一半
+func (b *Bloom) Save(path string) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := b.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,72 @@
+package dedup
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Filter 记录已经访问过的请求
+type Filter interface {
+	// Seen 返回key是否已经记录过，没有记录过时记录下来
+	Seen(key string) bool
+}
+
+// Canonicalize 规范化URL：scheme和host转为小写，去掉默认端口和fragment，空路径为"/"，查询参数按名称排序
+func Canonicalize(u *url.URL) string {
+	c := *u
+	c.Scheme = strings.ToLower(c.Scheme)
+	c.Host = strings.ToLower(c.Host)
+	if port := c.Port(); port == "80" && c.Scheme == "http" || port == "443" && c.Scheme == "https" {
+		c.Host = c.Hostname()
+		if strings.Contains(c.Host, ":") {
+			c.Host = "[" + c.Host + "]"
+		}
+	}
+	if c.Path == "" && c.Opaque == "" {
+		c.Path, c.RawPath = "/", ""
+	}
+	c.Fragment, c.RawFragment = "", ""
+	if c.RawQuery != "" {
+		if query, err := url.ParseQuery(c.RawQuery); err == nil {
+			c.RawQuery = query.Encode()
+		}
+	}
+	c.ForceQuery = false
+	return c.String()
+}
+
+// Key 请求的去重键，由请求方法、规范化的URL以及请求体的SHA1组成，读取请求体之后会恢复原来的内容
+func Key(req *http.Request) (string, error) {
+	var body []byte
+	switch {
+	case req.GetBody != nil:
+		r, err := req.GetBody()
+		if err != nil {
+			return "", err
+		}
+		body, err = ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return "", err
+		}
+	case req.Body != nil && req.Body != http.NoBody:
+		var err error
+		body, err = ioutil.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+	key := req.Method + " " + Canonicalize(req.URL)
+	if len(body) > 0 {
+		sum := sha1.Sum(body)
+		key += " " + hex.EncodeToString(sum[:])
+	}
+	return key, nil
+}
@@ -0,0 +1,102 @@
+package dedup
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	expect := map[string]string{
+		"HTTP://WWW.51Job.com:80":              "http://www.51job.com/",
+		"https://a.com:443/x?b=2&a=1#top":      "https://a.com/x?a=1&b=2",
+		"https://a.com:8443/x?":                "https://a.com:8443/x",
+		"http://a.com/list/020000,000000.html": "http://a.com/list/020000,000000.html",
+	}
+	for raw, canonical := range expect {
+		u, _ := url.Parse(raw)
+		if got := Canonicalize(u); got != canonical {
+			t.Errorf("%s: expect %s got %s", raw, canonical, got)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	get, _ := http.NewRequest("GET", "http://a.com/?b=1&a=2", nil)
+	get2, _ := http.NewRequest("GET", "http://A.com/?a=2&b=1#x", nil)
+	k1, _ := Key(get)
+	k2, _ := Key(get2)
+	if k1 != k2 {
+		t.Errorf("%s != %s", k1, k2)
+	}
+
+	post1, _ := http.NewRequest("POST", "http://a.com/", strings.NewReader("page=1"))
+	post2, _ := http.NewRequest("POST", "http://a.com/", strings.NewReader("page=2"))
+	p1, _ := Key(post1)
+	p2, _ := Key(post2)
+	if p1 == p2 || p1 == k1 {
+		t.Errorf("got %s %s", p1, p2)
+	}
+	// 读取请求体之后仍然可以发送
+	body, _ := ioutil.ReadAll(post1.Body)
+	if string(body) != "page=1" {
+		t.Errorf("got %s", body)
+	}
+
+	post3, _ := http.NewRequest("POST", "http://a.com/", ioutil.NopCloser(bytes.NewBufferString("page=1")))
+	post3.GetBody = nil
+	p3, _ := Key(post3)
+	body, _ = ioutil.ReadAll(post3.Body)
+	if p3 != p1 || string(body) != "page=1" {
+		t.Errorf("got %s %s", p3, body)
+	}
+}
+
+func TestBloom(t *testing.T) {
+	b := NewBloom(10000, 0.001)
+	for i := 0; i < 10000; i++ {
+		if b.Seen(fmt.Sprintf("GET http://a.com/%d", i)) {
+			t.Fatalf("%d should be new", i)
+		}
+	}
+	for i := 0; i < 10000; i++ {
+		if !b.Seen(fmt.Sprintf("GET http://a.com/%d", i)) {
+			t.Fatalf("%d should be seen", i)
+		}
+	}
+	fp := 0
+	for i := 0; i < 1000; i++ {
+		if b.Seen(fmt.Sprintf("GET http://b.com/%d", i)) {
+			fp++
+		}
+	}
+	if fp > 10 {
+		t.Errorf("too many false positives: %d", fp)
+	}
+
+	dir, err := ioutil.TempDir("", "bloom")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "seen.bloom")
+	if err := b.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := OpenBloom(path, 1, 0.1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Count() != b.Count() || !loaded.Seen("GET http://a.com/1") {
+		t.Errorf("got %d", loaded.Count())
+	}
+	if _, err := ReadBloom(strings.NewReader("xxxx")); err == nil {
+		t.Error("expect error")
+	}
+}
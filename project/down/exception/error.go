@@ -0,0 +1,62 @@
+package exception
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Fetch   ErrorType = "fetch"   // 请求失败，包括网络错误和状态码错误
+	Parse   ErrorType = "parse"   // 解析函数panic
+	Save    ErrorType = "save"    // 保存item失败
+	Unknown ErrorType = "unknown" // 不是*Error的错误
+)
+
+// Error 带有分类和请求信息的错误
+type Error struct {
+	Type    ErrorType
+	URL     string
+	Parser  string // 解析函数的名称
+	Attempt int    // 一共请求的次数
+	Err     error
+	Time    time.Time
+}
+
+func (e *Error) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s error", e.Type)
+	if e.URL != "" {
+		fmt.Fprintf(&b, " (url: %s", e.URL)
+		if e.Parser != "" {
+			fmt.Fprintf(&b, ", parser: %s", e.Parser)
+		}
+		if e.Attempt > 0 {
+			fmt.Fprintf(&b, ", attempt: %d", e.Attempt)
+		}
+		b.WriteString(")")
+	}
+	fmt.Fprintf(&b, ": %v", e.Err)
+	return b.String()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Host URL中的host，无法解析时为空
+func (e *Error) Host() string {
+	u, err := url.Parse(e.URL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Host)
+}
+
+// Report 错误的统计
+type Report struct {
+	Total int                          `json:"total"`
+	Types map[ErrorType]int            `json:"types"`
+	Hosts map[string]map[ErrorType]int `json:"hosts"` // 没有host的错误记录在""下
+}
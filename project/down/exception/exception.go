@@ -2,19 +2,32 @@ package exception
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"helper/logs"
+	"io"
+	"sync"
 	"sync/atomic"
 )
 
+// ErrorType 错误的分类
 type ErrorType string
 
+var _ DownloadError = (*donwloadError)(nil)
+
 type DownloadError interface {
 	Init(buffer uint32) error
 	SendError(err error)
+	// Start 开始处理错误，Close之后再次调用时重新创建channel并清空统计
 	Start()
 	Cancel() bool
+	// Close 处理完已经发送的错误之后返回，再次调用Start之前不能调用SendError
 	Close()
+	// Count 已经处理的t类错误的数量
+	Count(t ErrorType) int
+	Report() Report
+	// WriteReport 以JSON格式写入Report
+	WriteReport(w io.Writer) error
 }
 
 type donwloadError struct {
@@ -23,10 +36,23 @@ type donwloadError struct {
 	total   uint32
 	ctx     context.Context
 	ctxFunc context.CancelFunc
+	done    chan struct{} // Start启动的goroutine退出时关闭
+	closed  bool          // errChan已经被Close关闭
+
+	mu    sync.Mutex
+	types map[ErrorType]int
+	hosts map[string]map[ErrorType]int
 }
 
 func (d *donwloadError) Close() {
+	if d.closed {
+		return
+	}
+	d.closed = true
 	close(d.errChan)
+	if d.done != nil {
+		<-d.done
+	}
 }
 func (d *donwloadError) TotalHandler() uint32 {
 	return atomic.LoadUint32(&d.total)
@@ -50,6 +76,8 @@ func (d *donwloadError) Init(buffer uint32) error {
 	}
 
 	d.ctx, d.ctxFunc = context.WithCancel(context.Background())
+	d.types = make(map[ErrorType]int)
+	d.hosts = make(map[string]map[ErrorType]int)
 	return nil
 }
 
@@ -63,17 +91,35 @@ func (d *donwloadError) SendError(err error) {
 }
 
 func (d *donwloadError) Start() {
-
+	if d.closed {
+		// 上一轮已经Close，使用新的channel开始新一轮的统计
+		d.errChan = make(chan error, cap(d.errChan))
+		d.closed = false
+		atomic.StoreUint32(&d.total, 0)
+		d.mu.Lock()
+		d.types = make(map[ErrorType]int)
+		d.hosts = make(map[string]map[ErrorType]int)
+		d.mu.Unlock()
+	}
+	if d.ctx.Err() != nil {
+		d.ctx, d.ctxFunc = context.WithCancel(context.Background())
+	}
+	d.done = make(chan struct{})
 	go func(errChan chan error) {
 		defer func() {
 			logs.Info("Error Handler exited")
+			close(d.done)
 		}()
 		logs.Info("Start %s Error Handler", d.name)
 
 		for {
 			select {
-			case err := <-errChan:
+			case err, ok := <-errChan:
+				if !ok {
+					return
+				}
 				logs.Error("%s have error %s", d.name, err)
+				d.record(err)
 				atomic.AddUint32(&d.total, 1)
 			case <-d.ctx.Done():
 				logs.Info("Error Handler exiting...")
@@ -83,6 +129,51 @@ func (d *donwloadError) Start() {
 	}(d.errChan)
 }
 
+// record 按分类和host统计err
+func (d *donwloadError) record(err error) {
+	t, host := Unknown, ""
+	var e *Error
+	if errors.As(err, &e) {
+		t, host = e.Type, e.Host()
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.types[t]++
+	if d.hosts[host] == nil {
+		d.hosts[host] = make(map[ErrorType]int)
+	}
+	d.hosts[host][t]++
+}
+
+func (d *donwloadError) Count(t ErrorType) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.types[t]
+}
+
+func (d *donwloadError) Report() Report {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	r := Report{Types: make(map[ErrorType]int), Hosts: make(map[string]map[ErrorType]int)}
+	for t, n := range d.types {
+		r.Types[t] = n
+		r.Total += n
+	}
+	for host, types := range d.hosts {
+		r.Hosts[host] = make(map[ErrorType]int)
+		for t, n := range types {
+			r.Hosts[host][t] = n
+		}
+	}
+	return r
+}
+
+func (d *donwloadError) WriteReport(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(d.Report())
+}
+
 func NewError(buffer uint32, name string) *donwloadError {
 	d := &donwloadError{}
 	err := d.Init(buffer)
@@ -1,13 +1,16 @@
 package exception
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 )
 
 func TestNewError(t *testing.T) {
-	errs := NewError(10)
+	errs := NewError(10, "test")
 	if errs == nil {
 		t.Fatal("the Error Handler is nil")
 	}
@@ -25,9 +28,8 @@ func TestDonwloadError_Init(t *testing.T) {
 }
 
 func TestDonwloadError_SendError(t *testing.T) {
-	errs := NewError(10)
+	errs := NewError(10, "test")
 	errs.Start()
-	errs.Notify()
 
 	errs.SendError(errors.New("exception1"))
 	errs.SendError(errors.New("exception1"))
@@ -38,9 +40,77 @@ func TestDonwloadError_SendError(t *testing.T) {
 }
 
 func TestDonwloadError_Start(t *testing.T) {
-	errs := NewError(10)
+	errs := NewError(10, "test")
 	errs.Start()
-	errs.Notify()
 	errs.Cancel()
 
 }
+
+func TestDonwloadError_Report(t *testing.T) {
+	errs := NewError(10, "test")
+	errs.Start()
+	errs.SendError(&Error{Type: Fetch, URL: "http://A.com/1", Attempt: 3, Err: errors.New("503")})
+	errs.SendError(&Error{Type: Fetch, URL: "http://a.com/2", Err: errors.New("404")})
+	errs.SendError(fmt.Errorf("wrapped: %w", &Error{Type: Parse, URL: "http://b.com/", Err: errors.New("panic")}))
+	errs.SendError(&Error{Type: Save, Err: errors.New("disk full")})
+	errs.SendError(errors.New("other"))
+	errs.SendError(nil)
+	// Close之前发送的错误都会被处理
+	errs.Close()
+
+	if errs.Count(Fetch) != 2 || errs.Count(Parse) != 1 || errs.Count(Save) != 1 || errs.Count(Unknown) != 1 {
+		t.Errorf("got %+v", errs.Report())
+	}
+	if errs.TotalHandler() != 5 {
+		t.Errorf("got %d", errs.TotalHandler())
+	}
+
+	var buf bytes.Buffer
+	if err := errs.WriteReport(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var r Report
+	if err := json.Unmarshal(buf.Bytes(), &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Total != 5 || r.Types[Fetch] != 2 || r.Hosts["a.com"][Fetch] != 2 || r.Hosts["b.com"][Parse] != 1 ||
+		r.Hosts[""][Save] != 1 || r.Hosts[""][Unknown] != 1 {
+		t.Errorf("got %s", buf.String())
+	}
+}
+
+func TestDonwloadError_Restart(t *testing.T) {
+	errs := NewError(10, "test")
+	for i := 0; i < 2; i++ {
+		errs.Start()
+		errs.SendError(&Error{Type: Fetch, URL: "http://a.com/", Err: errors.New("404")})
+		errs.Close()
+		if errs.Count(Fetch) != 1 || errs.TotalHandler() != 1 {
+			t.Errorf("round %d: got %+v", i, errs.Report())
+		}
+	}
+	// 重复Close不会panic
+	errs.Close()
+	errs.Cancel()
+	errs.Start()
+	errs.SendError(errors.New("after cancel"))
+	errs.Close()
+	if errs.Count(Unknown) != 1 {
+		t.Errorf("got %+v", errs.Report())
+	}
+}
+
+func TestError(t *testing.T) {
+	cause := errors.New("unsupported status code 503")
+	err := &Error{Type: Fetch, URL: "http://a.com/", Parser: "down/project.ParserJob", Attempt: 3, Err: cause}
+	expect := "fetch error (url: http://a.com/, parser: down/project.ParserJob, attempt: 3): unsupported status code 503"
+	if err.Error() != expect {
+		t.Errorf("got %s", err)
+	}
+	if !errors.Is(err, cause) {
+		t.Error("expect unwrap")
+	}
+	if s := (&Error{Type: Save, Err: cause}).Error(); s != "save error: unsupported status code 503" {
+		t.Errorf("got %s", s)
+	}
+}
@@ -1,13 +1,49 @@
 package main
 
 import (
+	"context"
 	"down/core"
 	"down/project"
+	"down/saver"
+	"fmt"
+	"os"
+	"os/signal"
 )
 
-
 func main() {
 	req := core.NewGetRequest(project.GetUrl(1), project.ParserCompany)
-	eng := core.NewEngine()
-	eng.Run(req)
+	eng := core.NewEngine(core.WithScheduler(&core.PriorityScheduler{}))
+	items, err := saver.Create("items.jsonl", saver.NewJSONLines)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer items.Close()
+	eng.Saver = items
+	failed, err := os.OpenFile("failed.jsonl", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer failed.Close()
+	eng.FailedLog = core.NewFailedLog(failed)
+	report, err := os.Create("errors.json")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer report.Close()
+	eng.Report = report
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	go func() {
+		<-sig
+		cancel()
+	}()
+
+	res := eng.Run(ctx, req)
+	fmt.Printf("requests: %d, failed: %d, retries: %d, items: %d, duration: %s\n", res.Requests, res.Failed, res.Retries, res.Items, res.Duration)
 }
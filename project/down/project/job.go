@@ -5,7 +5,6 @@ import (
 	"down/core"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
-	"github.com/djimenez/iconv-go"
 	"strconv"
 	"strings"
 )
@@ -18,11 +17,7 @@ func ParserJob(content []byte) core.Response {
 	res := core.NewRequestResult()
 	doc.Find("div").Each(func(i int, selection *goquery.Selection) {
 		if selection.HasClass("job_msg") {
-			str := selection.Text()
-			c, err := iconv.ConvertString(str, "gb2312", "utf-8")
-			if err != nil {
-				fmt.Println(err)
-			}
+			c := selection.Text()
 			if c == "" {
 				return
 			}
@@ -44,7 +39,10 @@ func ParserCompany(content []byte) core.Response {
 			strings.HasPrefix(href, "#") || !strings.Contains(href, "jobs") {
 			return
 		}
-		res.AppendRequest(core.NewGetRequest(href, ParserJob))
+		// 详情页优先处理，尽早得到item
+		req := core.NewGetRequest(href, ParserJob)
+		req.Priority = 1
+		res.AppendRequest(req)
 	})
 
 
@@ -0,0 +1,167 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Options 每个host使用相同的配置，Rate为0时不限速
+type Options struct {
+	Rate    float64       // 每秒请求数
+	Burst   int           // 令牌桶容量，至少为1
+	Jitter  time.Duration // 每次请求额外等待[0, Jitter)的随机时间
+	MinRate float64       // 收到429/503时降速的下限，默认为Rate的1/16
+}
+
+// Limiter 按host限速的令牌桶，所有worker共享，收到429/503时该host的速率减半，之后每次成功恢复Rate的1/10
+type Limiter struct {
+	opt   Options
+	mu    sync.Mutex
+	hosts map[string]*bucket
+	rand  *rand.Rand
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+type bucket struct {
+	rate   float64 // 当前的速率
+	limit  float64 // 速率上限，Crawl-delay会降低上限，为0时不限速
+	burst  float64 // 令牌桶容量，设置了Crawl-delay时为1
+	tokens float64
+	last   time.Time
+}
+
+func New(opt Options) *Limiter {
+	if opt.Burst < 1 {
+		opt.Burst = 1
+	}
+	if opt.MinRate <= 0 || opt.MinRate > opt.Rate {
+		opt.MinRate = opt.Rate / 16
+	}
+	return &Limiter{
+		opt:   opt,
+		hosts: make(map[string]*bucket),
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		now:   time.Now,
+		sleep: sleep,
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func hostKey(host string) string {
+	return strings.ToLower(host)
+}
+
+func (l *Limiter) bucket(host string, now time.Time) *bucket {
+	b, ok := l.hosts[hostKey(host)]
+	if !ok {
+		b = &bucket{rate: l.opt.Rate, limit: l.opt.Rate, burst: float64(l.opt.Burst), tokens: float64(l.opt.Burst), last: now}
+		l.hosts[hostKey(host)] = b
+	}
+	return b
+}
+
+// reserve 取出一个令牌，返回需要等待的时间，令牌不足时预支后面的令牌
+func (l *Limiter) reserve(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	b := l.bucket(host, now)
+	if b.rate <= 0 {
+		return 0
+	}
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	b.tokens--
+
+	var wait time.Duration
+	if b.tokens < 0 {
+		wait = time.Duration(-b.tokens / b.rate * float64(time.Second))
+	}
+	if l.opt.Jitter > 0 {
+		wait += time.Duration(l.rand.Int63n(int64(l.opt.Jitter)))
+	}
+	return wait
+}
+
+// Wait 等待直到可以向host发送请求，ctx被取消时返回ctx.Err()
+func (l *Limiter) Wait(ctx context.Context, host string) error {
+	return l.sleep(ctx, l.reserve(host))
+}
+
+// Feedback 根据响应的状态码调整host的速率
+func (l *Limiter) Feedback(host string, status int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(host, l.now())
+	if b.rate <= 0 {
+		return
+	}
+	switch {
+	case status == http.StatusTooManyRequests || status == http.StatusServiceUnavailable:
+		b.rate /= 2
+		if min := math.Min(l.opt.MinRate, b.limit); b.rate < min {
+			b.rate = min
+		}
+		if b.tokens > 0 {
+			b.tokens = 0
+		}
+	case status >= 200 && status < 400 && b.rate < b.limit:
+		b.rate += b.limit / 10
+		if b.rate > b.limit {
+			b.rate = b.limit
+		}
+	}
+}
+
+// SetDelay 设置host两次请求之间至少间隔的时间，例如robots.txt中的Crawl-delay，
+// 只会降低速率，不会超过Options.Rate
+func (l *Limiter) SetDelay(host string, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.bucket(host, l.now())
+	limit := float64(time.Second) / float64(d)
+	if b.limit > 0 && b.limit <= limit {
+		return
+	}
+	b.limit = limit
+	if b.rate <= 0 || b.rate > limit {
+		b.rate = limit
+	}
+	// 不允许连续发送多个请求
+	b.burst = 1
+	if b.tokens > 1 {
+		b.tokens = 1
+	}
+}
+
+// Rate 返回host当前的速率
+func (l *Limiter) Rate(host string) float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.bucket(host, l.now()).rate
+}
@@ -0,0 +1,129 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	now   time.Time
+	waits []time.Duration
+}
+
+func newTestLimiter(opt Options) (*Limiter, *clock) {
+	c := &clock{now: time.Date(2019, 6, 3, 10, 0, 0, 0, time.UTC)}
+	l := New(opt)
+	l.now = func() time.Time { return c.now }
+	l.sleep = func(ctx context.Context, d time.Duration) error {
+		c.waits = append(c.waits, d)
+		c.now = c.now.Add(d)
+		return nil
+	}
+	return l, c
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	l, c := newTestLimiter(Options{Rate: 10, Burst: 2})
+	for i := 0; i < 4; i++ {
+		l.Wait(context.Background(), "a.com")
+	}
+	expect := []time.Duration{0, 0, 100 * time.Millisecond, 100 * time.Millisecond}
+	for i, d := range expect {
+		if c.waits[i] != d {
+			t.Errorf("%d: expect %s got %s", i, d, c.waits[i])
+		}
+	}
+	// 不同的host互不影响
+	l.Wait(context.Background(), "B.com")
+	if w := c.waits[4]; w != 0 {
+		t.Errorf("got %s", w)
+	}
+	// 空闲之后令牌最多恢复到Burst
+	c.now = c.now.Add(time.Minute)
+	c.waits = nil
+	for i := 0; i < 3; i++ {
+		l.Wait(context.Background(), "a.com")
+	}
+	if c.waits[1] != 0 || c.waits[2] != 100*time.Millisecond {
+		t.Errorf("got %v", c.waits)
+	}
+}
+
+func TestLimiter_Jitter(t *testing.T) {
+	l, c := newTestLimiter(Options{Rate: 1000, Burst: 100, Jitter: 50 * time.Millisecond})
+	for i := 0; i < 20; i++ {
+		l.Wait(context.Background(), "a.com")
+	}
+	for _, d := range c.waits {
+		if d < 0 || d >= 50*time.Millisecond {
+			t.Errorf("got %s", d)
+		}
+	}
+}
+
+func TestLimiter_Feedback(t *testing.T) {
+	l, _ := newTestLimiter(Options{Rate: 8, MinRate: 1})
+	for i := 0; i < 5; i++ {
+		l.Feedback("a.com", http.StatusTooManyRequests)
+	}
+	if r := l.Rate("a.com"); r != 1 {
+		t.Errorf("expect 1 got %v", r)
+	}
+	l.Feedback("a.com", 0)
+	l.Feedback("a.com", http.StatusNotFound)
+	if r := l.Rate("a.com"); r != 1 {
+		t.Errorf("expect 1 got %v", r)
+	}
+	for i := 0; i < 20; i++ {
+		l.Feedback("a.com", http.StatusOK)
+	}
+	if r := l.Rate("a.com"); r != 8 {
+		t.Errorf("expect 8 got %v", r)
+	}
+}
+
+func TestLimiter_Cancel(t *testing.T) {
+	l := New(Options{Rate: 1})
+	l.Wait(context.Background(), "a.com")
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := l.Wait(ctx, "a.com"); err != context.DeadlineExceeded {
+		t.Errorf("got %v", err)
+	}
+}
+
+func TestLimiter_SetDelay(t *testing.T) {
+	l, c := newTestLimiter(Options{Rate: 10, Burst: 5})
+	l.SetDelay("a.com", 2*time.Second)
+	if r := l.Rate("a.com"); r != 0.5 {
+		t.Errorf("got %v", r)
+	}
+	for i := 0; i < 3; i++ {
+		l.Wait(context.Background(), "a.com")
+	}
+	// 设置之后不能再连续发送请求
+	if c.waits[1] != 2*time.Second || c.waits[2] != 2*time.Second {
+		t.Errorf("got %v", c.waits)
+	}
+	// 成功的请求最多恢复到Crawl-delay的速率
+	l.Feedback("a.com", http.StatusTooManyRequests)
+	for i := 0; i < 20; i++ {
+		l.Feedback("a.com", http.StatusOK)
+	}
+	if r := l.Rate("a.com"); r != 0.5 {
+		t.Errorf("got %v", r)
+	}
+	// 比Rate更快的Crawl-delay不会提高速率
+	l.SetDelay("b.com", time.Millisecond)
+	if r := l.Rate("b.com"); r != 10 {
+		t.Errorf("got %v", r)
+	}
+	// 不限速时也会遵守Crawl-delay
+	l, _ = newTestLimiter(Options{})
+	l.SetDelay("a.com", time.Second)
+	if r := l.Rate("a.com"); r != 1 {
+		t.Errorf("got %v", r)
+	}
+}
@@ -0,0 +1,132 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// maxSize robots.txt最多读取的字节数，超出的部分被忽略
+const maxSize = 500 << 10
+
+// Cache 按host获取并缓存robots.txt，所有worker共享，同一个host同时只会请求一次
+type Cache struct {
+	Agent  string        // 匹配分组使用的user-agent，同时作为请求robots.txt的User-Agent
+	TTL    time.Duration // 缓存时间，过期后重新获取
+	client *http.Client
+	mu     sync.Mutex
+	hosts  map[string]*entry
+	now    func() time.Time
+}
+
+type entry struct {
+	ready   chan struct{} // 获取完成后关闭
+	robots  *Robots
+	err     error // 获取失败的原因，此时robots禁止访问整个host
+	expires time.Time
+}
+
+// NewCache client为nil时使用30秒超时的默认客户端
+func NewCache(agent string, client *http.Client) *Cache {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Cache{
+		Agent:  agent,
+		TTL:    24 * time.Hour,
+		client: client,
+		hosts:  make(map[string]*entry),
+		now:    time.Now,
+	}
+}
+
+// Get 返回u所在host的robots.txt
+func (c *Cache) Get(ctx context.Context, u *url.URL) *Robots {
+	r, _ := c.get(ctx, u)
+	return r
+}
+
+// get 返回u所在host的robots.txt，获取失败时返回禁止访问整个host的规则以及失败的原因
+func (c *Cache) get(ctx context.Context, u *url.URL) (*Robots, error) {
+	key := u.Scheme + "://" + u.Host
+	c.mu.Lock()
+	e, ok := c.hosts[key]
+	if ok {
+		select {
+		case <-e.ready:
+			ok = c.now().Before(e.expires)
+		default:
+		}
+	}
+	if !ok {
+		e = &entry{ready: make(chan struct{})}
+		c.hosts[key] = e
+		c.mu.Unlock()
+		e.robots, e.expires, e.err = c.fetch(ctx, key)
+		close(e.ready)
+		return e.robots, e.err
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-e.ready:
+		return e.robots, e.err
+	case <-ctx.Done():
+		return disallowAll, ctx.Err()
+	}
+}
+
+// Check 返回u是否允许访问以及host要求的Crawl-delay，robots.txt本身总是允许访问，
+// robots.txt获取失败而暂时禁止访问整个host时返回失败的原因
+func (c *Cache) Check(ctx context.Context, u *url.URL) (bool, time.Duration, error) {
+	if u.Path == "/robots.txt" {
+		return true, 0, nil
+	}
+	r, err := c.get(ctx, u)
+	if err != nil {
+		return false, 0, err
+	}
+	g := r.Group(c.Agent)
+	return g.Allowed(u.RequestURI()), g.CrawlDelay, nil
+}
+
+// fetch 4xx表示没有限制，429、5xx或者网络错误时暂时禁止访问整个host并返回错误，ctx被取消时结果不缓存
+func (c *Cache) fetch(ctx context.Context, base string) (*Robots, time.Time, error) {
+	now := c.now()
+	req, err := http.NewRequest("GET", base+"/robots.txt", nil)
+	if err != nil {
+		return disallowAll, now.Add(c.TTL), err
+	}
+	req = req.WithContext(ctx)
+	if c.Agent != "" {
+		req.Header.Set("User-Agent", c.Agent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return disallowAll, now, ctx.Err()
+		}
+		return disallowAll, now.Add(c.retryAfter()), fmt.Errorf("fetch %s/robots.txt: %w", base, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return Parse(io.LimitReader(resp.Body, maxSize)), now.Add(c.TTL), nil
+	case resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests:
+		return allowAll, now.Add(c.TTL), nil
+	default:
+		return disallowAll, now.Add(c.retryAfter()), fmt.Errorf("fetch %s/robots.txt: unexpected status code %d", base, resp.StatusCode)
+	}
+}
+
+// retryAfter 获取失败时禁止访问的时间，之后重新获取
+func (c *Cache) retryAfter() time.Duration {
+	if c.TTL < time.Hour {
+		return c.TTL
+	}
+	return time.Hour
+}
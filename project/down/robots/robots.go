@@ -0,0 +1,158 @@
+package robots
+
+import (
+	"bufio"
+	"io"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Robots 解析后的robots.txt，零值允许所有请求
+type Robots struct {
+	groups []*group
+}
+
+type group struct {
+	agents []string
+	Group
+}
+
+// Group 一个user-agent分组的规则
+type Group struct {
+	Rules      []Rule
+	CrawlDelay time.Duration
+}
+
+// Rule Path中"*"匹配任意字符，结尾的"$"表示匹配到路径结束
+type Rule struct {
+	Allow bool
+	Path  string
+}
+
+var (
+	allowAll    = &Robots{}
+	disallowAll = &Robots{groups: []*group{{agents: []string{"*"}, Group: Group{Rules: []Rule{{Path: "/"}}}}}}
+)
+
+// Parse 解析robots.txt，无法识别的行会被忽略
+func Parse(r io.Reader) *Robots {
+	res := &Robots{}
+	var cur *group
+	inRules := false // 当前分组已经出现过规则，再遇到user-agent时开始新的分组
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		i := strings.IndexByte(line, ':')
+		if i < 0 {
+			continue
+		}
+		key := strings.ToLower(strings.TrimSpace(line[:i]))
+		value := strings.TrimSpace(line[i+1:])
+		switch key {
+		case "user-agent":
+			if cur == nil || inRules {
+				cur = &group{}
+				res.groups = append(res.groups, cur)
+				inRules = false
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			// 空的Disallow表示允许所有请求
+			if value != "" {
+				cur.Rules = append(cur.Rules, Rule{Allow: key == "allow", Path: value})
+			}
+		case "crawl-delay":
+			if cur == nil {
+				continue
+			}
+			inRules = true
+			if sec, err := strconv.ParseFloat(value, 64); err == nil && sec > 0 {
+				cur.CrawlDelay = time.Duration(sec * float64(time.Second))
+			}
+		}
+	}
+	return res
+}
+
+// Group 返回agent适用的规则：名称是agent前缀的分组中最长的一个，同名的分组会合并，没有时使用"*"分组
+func (r *Robots) Group(agent string) Group {
+	agent = strings.ToLower(agent)
+	best := ""
+	for _, g := range r.groups {
+		for _, a := range g.agents {
+			if a != "*" && strings.HasPrefix(agent, a) && len(a) > len(best) {
+				best = a
+			}
+		}
+	}
+	if best == "" {
+		best = "*"
+	}
+	var res Group
+	for _, g := range r.groups {
+		for _, a := range g.agents {
+			if a == best {
+				res.Rules = append(res.Rules, g.Rules...)
+				if g.CrawlDelay > res.CrawlDelay {
+					res.CrawlDelay = g.CrawlDelay
+				}
+				break
+			}
+		}
+	}
+	return res
+}
+
+// Allowed 使用匹配path最长的规则，长度相同时Allow优先，没有匹配的规则时允许
+func (g Group) Allowed(path string) bool {
+	if path == "" {
+		path = "/"
+	}
+	allowed, length := true, -1
+	for _, r := range g.Rules {
+		if !match(r.Path, path) {
+			continue
+		}
+		if len(r.Path) > length || len(r.Path) == length && r.Allow {
+			allowed, length = r.Allow, len(r.Path)
+		}
+	}
+	return allowed
+}
+
+// Allowed 返回agent是否可以访问u
+func (r *Robots) Allowed(agent string, u *url.URL) bool {
+	return r.Group(agent).Allowed(u.RequestURI())
+}
+
+func match(pattern, path string) bool {
+	end := strings.HasSuffix(pattern, "$")
+	if end {
+		pattern = pattern[:len(pattern)-1]
+	}
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	pos := len(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if end && i == len(parts)-1 {
+			return strings.HasSuffix(path[pos:], parts[i])
+		}
+		j := strings.Index(path[pos:], parts[i])
+		if j < 0 {
+			return false
+		}
+		pos += j + len(parts[i])
+	}
+	return !end || pos == len(path)
+}
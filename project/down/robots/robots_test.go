@@ -0,0 +1,188 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+const testRobots = `
+# comment
+User-agent: *
+Disallow: /private
+Allow: /private/public
+Disallow: /*.pdf$
+Disallow: /search?*q=
+
+User-agent: down
+User-agent: other
+Disallow: /admin   # inline comment
+Crawl-delay: 1.5
+
+User-agent: Down-Image
+Disallow: /
+
+user-agent: down
+disallow: /tmp/
+`
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/", "/a", true},
+		{"/a", "/abc", true},
+		{"/a", "/b", false},
+		{"/*.pdf$", "/doc/a.pdf", true},
+		{"/*.pdf$", "/doc/a.pdf?x=1", false},
+		{"/*.pdf", "/doc/a.pdf?x=1", true},
+		{"/a$", "/a", true},
+		{"/a$", "/ab", false},
+		{"/a*b*c", "/a-b-c-d", true},
+		{"/a*b*c", "/a-c-b", false},
+		{"*", "/anything", true},
+		{"/a*$", "/a/b", true},
+	}
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestRobots_Group(t *testing.T) {
+	r := Parse(strings.NewReader(testRobots))
+
+	star := r.Group("crawler")
+	for path, want := range map[string]bool{
+		"/":                     true,
+		"/private":              false,
+		"/private/x":            false,
+		"/private/public/x":     true,
+		"/a.pdf":                false,
+		"/a.pdf.html":           true,
+		"/search?page=1&q=x":    false,
+		"/search?page=1":        true,
+		"":                      true,
+		"/admin":                true,
+		"/tmp/a":                true,
+		"/private/public/a.pdf": true,
+	} {
+		if got := star.Allowed(path); got != want {
+			t.Errorf("*: Allowed(%q) = %v, want %v", path, got, want)
+		}
+	}
+	if star.CrawlDelay != 0 {
+		t.Errorf("got %s", star.CrawlDelay)
+	}
+
+	// 同名的分组会合并，不再使用*分组
+	down := r.Group("down/1.0")
+	if down.Allowed("/admin/x") || down.Allowed("/tmp/a") || !down.Allowed("/private") {
+		t.Errorf("got %+v", down)
+	}
+	if down.CrawlDelay != 1500*time.Millisecond {
+		t.Errorf("got %s", down.CrawlDelay)
+	}
+
+	// 匹配最长的名称，不区分大小写
+	if r.Group("down-image").Allowed("/a") {
+		t.Error("expect down-image disallowed")
+	}
+
+	if !Parse(strings.NewReader("")).Allowed("down", &url.URL{Path: "/a"}) {
+		t.Error("empty robots.txt should allow all")
+	}
+	if !Parse(strings.NewReader("User-agent: *\nDisallow:\n")).Group("down").Allowed("/a") {
+		t.Error("empty disallow should allow all")
+	}
+}
+
+func TestCache(t *testing.T) {
+	var fetches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/robots.txt" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		if ua := r.Header.Get("User-Agent"); ua != "down" {
+			t.Errorf("got user-agent %q", ua)
+		}
+		atomic.AddInt32(&fetches, 1)
+		time.Sleep(10 * time.Millisecond)
+		fmt.Fprint(w, testRobots)
+	}))
+	defer server.Close()
+
+	c := NewCache("down", nil)
+	u, _ := url.Parse(server.URL + "/admin/a")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			allowed, delay, err := c.Check(context.Background(), u)
+			if allowed || delay != 1500*time.Millisecond || err != nil {
+				t.Errorf("got %v %s %v", allowed, delay, err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&fetches); n != 1 {
+		t.Errorf("fetched %d times", n)
+	}
+
+	// 过期之后重新获取
+	now := time.Now()
+	c.now = func() time.Time { return now.Add(25 * time.Hour) }
+	u, _ = url.Parse(server.URL + "/robots.txt")
+	if allowed, _, _ := c.Check(context.Background(), u); !allowed {
+		t.Error("robots.txt should always be allowed")
+	}
+	u, _ = url.Parse(server.URL + "/")
+	c.Check(context.Background(), u)
+	if n := atomic.LoadInt32(&fetches); n != 2 {
+		t.Errorf("fetched %d times", n)
+	}
+}
+
+func TestCache_Status(t *testing.T) {
+	tests := []struct {
+		status  int
+		allowed bool
+		failed  bool // 获取失败，返回错误
+	}{
+		{http.StatusOK, true, false},
+		{http.StatusNotFound, true, false},
+		{http.StatusForbidden, true, false},
+		{http.StatusTooManyRequests, false, true},
+		{http.StatusInternalServerError, false, true},
+		{http.StatusServiceUnavailable, false, true},
+	}
+	for _, tt := range tests {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tt.status)
+		}))
+		u, _ := url.Parse(server.URL + "/a")
+		allowed, _, err := NewCache("down", nil).Check(context.Background(), u)
+		if allowed != tt.allowed || (err != nil) != tt.failed {
+			t.Errorf("status %d: got %v %v", tt.status, allowed, err)
+		}
+		server.Close()
+	}
+
+	// 网络错误
+	server := httptest.NewServer(http.NotFoundHandler())
+	u, _ := url.Parse(server.URL + "/a")
+	server.Close()
+	if allowed, _, err := NewCache("down", nil).Check(context.Background(), u); allowed || err == nil {
+		t.Errorf("got %v %v", allowed, err)
+	}
+}
@@ -0,0 +1,103 @@
+package saver
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// csvSaver 第一个item决定表头：结构体使用导出的字段，列名依次取`csv`、`json`标签和字段名，
+// map使用排序后的键，其他类型只有一列value。后面的item中表头没有的字段会被忽略
+type csvSaver struct {
+	w      *csv.Writer
+	header []string
+}
+
+func NewCSV(w io.Writer) Saver {
+	return &csvSaver{w: csv.NewWriter(w)}
+}
+
+func (c *csvSaver) Save(item interface{}) error {
+	names, values := columns(item)
+	if c.header == nil {
+		c.header = names
+		if err := c.w.Write(c.header); err != nil {
+			return err
+		}
+	}
+	row := make([]string, len(c.header))
+	for i, name := range c.header {
+		row[i] = values[name]
+	}
+	if err := c.w.Write(row); err != nil {
+		return err
+	}
+	return c.w.Error()
+}
+
+func (c *csvSaver) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvSaver) Close() error {
+	return c.Flush()
+}
+
+func columns(item interface{}) ([]string, map[string]string) {
+	v := reflect.ValueOf(item)
+	for v.Kind() == reflect.Ptr && !v.IsNil() {
+		v = v.Elem()
+	}
+	values := make(map[string]string)
+	var names []string
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name, ok := columnName(field)
+			if !ok {
+				continue
+			}
+			names = append(names, name)
+			values[name] = fmt.Sprint(v.Field(i).Interface())
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() == reflect.String {
+			for _, key := range v.MapKeys() {
+				names = append(names, key.String())
+				values[key.String()] = fmt.Sprint(v.MapIndex(key).Interface())
+			}
+			sort.Strings(names)
+			break
+		}
+		fallthrough
+	default:
+		names = []string{"value"}
+		values["value"] = fmt.Sprint(item)
+	}
+	return names, values
+}
+
+func columnName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	for _, key := range []string{"csv", "json"} {
+		tag := field.Tag.Get(key)
+		if idx := strings.Index(tag, ","); idx >= 0 {
+			tag = tag[:idx]
+		}
+		if tag == "-" {
+			return "", false
+		}
+		if tag != "" {
+			return tag, true
+		}
+	}
+	return field.Name, true
+}
@@ -0,0 +1,32 @@
+package saver
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// jsonLines 每个item一行JSON
+type jsonLines struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func NewJSONLines(w io.Writer) Saver {
+	buf := bufio.NewWriter(w)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	return &jsonLines{w: buf, enc: enc}
+}
+
+func (j *jsonLines) Save(item interface{}) error {
+	return j.enc.Encode(item)
+}
+
+func (j *jsonLines) Flush() error {
+	return j.w.Flush()
+}
+
+func (j *jsonLines) Close() error {
+	return j.Flush()
+}
@@ -0,0 +1,120 @@
+package saver
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RotateOptions 满足任意一个条件时切换到新的文件，都为0时不切分
+type RotateOptions struct {
+	MaxSize  int64         // 单个文件的最大字节数，按已经刷新到文件的字节计算，可能略微超出
+	Interval time.Duration // 单个文件的最长写入时间
+}
+
+// Rotating 按大小或时间切分文件，文件名为"name-20060102-150405-序号.ext"，切换和Close时同步到磁盘
+type Rotating struct {
+	path    string
+	format  Format
+	opt     RotateOptions
+	current Saver
+	counter *countWriter
+	file    *os.File
+	opened  time.Time
+	seq     int
+	files   []string
+	now     func() time.Time
+}
+
+type countWriter struct {
+	f *os.File
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.f.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+func NewRotating(path string, format Format, opt RotateOptions) (*Rotating, error) {
+	if opt.MaxSize < 0 || opt.Interval < 0 {
+		return nil, errors.New("invalid rotate options")
+	}
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Rotating{path: path, format: format, opt: opt, now: time.Now}, nil
+}
+
+// Files 已经创建的文件，按创建顺序排列
+func (r *Rotating) Files() []string {
+	return r.files
+}
+
+func (r *Rotating) Save(item interface{}) error {
+	if r.current != nil && r.expired() {
+		if err := r.closeFile(); err != nil {
+			return err
+		}
+	}
+	if r.current == nil {
+		if err := r.open(); err != nil {
+			return err
+		}
+	}
+	return r.current.Save(item)
+}
+
+func (r *Rotating) expired() bool {
+	if r.opt.MaxSize > 0 && r.counter.n >= r.opt.MaxSize {
+		return true
+	}
+	return r.opt.Interval > 0 && r.now().Sub(r.opened) >= r.opt.Interval
+}
+
+func (r *Rotating) open() error {
+	r.opened = r.now()
+	r.seq++
+	ext := filepath.Ext(r.path)
+	name := fmt.Sprintf("%s-%s-%d%s", strings.TrimSuffix(r.path, ext), r.opened.Format("20060102-150405"), r.seq, ext)
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	r.file = f
+	r.counter = &countWriter{f: f}
+	r.current = r.format(r.counter)
+	r.files = append(r.files, name)
+	return nil
+}
+
+func (r *Rotating) closeFile() error {
+	err := r.current.Close()
+	if e := r.file.Sync(); err == nil {
+		err = e
+	}
+	if e := r.file.Close(); err == nil {
+		err = e
+	}
+	r.current, r.file, r.counter = nil, nil, nil
+	return err
+}
+
+func (r *Rotating) Flush() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.current.Flush()
+}
+
+func (r *Rotating) Close() error {
+	if r.current == nil {
+		return nil
+	}
+	return r.closeFile()
+}
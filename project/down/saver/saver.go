@@ -1,22 +1,51 @@
 package saver
 
 import (
-	"fmt"
+	"io"
 	"os"
 )
 
-func Save() chan interface{} {
-	out := make(chan interface{})
-	go func() {
-		count := 0
-		f, _ := os.OpenFile("test.txt", os.O_CREATE|os.O_APPEND|os.O_RDWR, 0666)
-		defer f.Close()
-
-		for {
-			item := <-out
-			f.WriteString(fmt.Sprintf("第 %d 条数据，内容: %s \n", count, item))
-			count++
-		}
-	}()
-	return out
+// Saver 保存引擎解析出来的item，Save不要求并发安全，引擎只在一个goroutine中调用
+type Saver interface {
+	Save(item interface{}) error
+	Flush() error
+	Close() error
 }
+
+// Format 在w上创建指定格式的Saver，例如NewJSONLines、NewCSV，Close时只刷新缓冲不关闭w
+type Format func(w io.Writer) Saver
+
+// fileSaver 拥有文件的Saver，Close时刷新缓冲、同步到磁盘并关闭文件
+type fileSaver struct {
+	Saver
+	file *os.File
+}
+
+// Create 创建或清空path，使用format写入
+func Create(path string, format Format) (Saver, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &fileSaver{Saver: format(f), file: f}, nil
+}
+
+func (s *fileSaver) Close() error {
+	err := s.Saver.Close()
+	if e := s.file.Sync(); err == nil {
+		err = e
+	}
+	if e := s.file.Close(); err == nil {
+		err = e
+	}
+	return err
+}
+
+type discard struct{}
+
+func (discard) Save(interface{}) error { return nil }
+func (discard) Flush() error           { return nil }
+func (discard) Close() error           { return nil }
+
+// Discard 丢弃所有的item
+var Discard Saver = discard{}
@@ -0,0 +1,123 @@
+package saver
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type job struct {
+	Title  string `json:"title"`
+	Salary int    `csv:"salary_k"`
+	URL    string
+	secret string
+}
+
+func TestJSONLines(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewJSONLines(&buf)
+	s.Save(job{Title: "golang <dev>", Salary: 20})
+	s.Save("text")
+	if buf.Len() != 0 {
+		t.Error("expect buffered")
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expect := `{"title":"golang <dev>","Salary":20,"URL":""}` + "\n\"text\"\n"
+	if buf.String() != expect {
+		t.Errorf("got %s", buf.String())
+	}
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	s := NewCSV(&buf)
+	s.Save(&job{Title: "golang, dev", Salary: 20, URL: "http://a"})
+	s.Save(job{Title: "java", Salary: 15})
+	s.Flush()
+	expect := "title,salary_k,URL\n\"golang, dev\",20,http://a\njava,15,\n"
+	if buf.String() != expect {
+		t.Errorf("got %s", buf.String())
+	}
+
+	buf.Reset()
+	s = NewCSV(&buf)
+	s.Save(map[string]interface{}{"b": 1, "a": "x"})
+	s.Save(map[string]interface{}{"a": "y", "c": 2})
+	s.Flush()
+	if expect := "a,b\nx,1\ny,\n"; buf.String() != expect {
+		t.Errorf("got %s", buf.String())
+	}
+}
+
+func TestRotating(t *testing.T) {
+	dir, err := ioutil.TempDir("", "saver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := NewRotating(filepath.Join(dir, "out", "items.csv"), NewCSV, RotateOptions{MaxSize: 1, Interval: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2019, 6, 3, 10, 0, 0, 0, time.Local)
+	r.now = func() time.Time { return now }
+	for _, item := range []string{"a", "b", "c"} {
+		if err := r.Save(item); err != nil {
+			t.Fatal(err)
+		}
+		r.Flush()
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files := r.Files()
+	if len(files) != 3 || filepath.Base(files[2]) != "items-20190603-100000-3.csv" {
+		t.Fatalf("got %v", files)
+	}
+	data, _ := ioutil.ReadFile(files[1])
+	if string(data) != "value\nb\n" {
+		t.Errorf("got %q", data)
+	}
+
+	r, _ = NewRotating(filepath.Join(dir, "items.jsonl"), NewJSONLines, RotateOptions{Interval: time.Minute})
+	r.now = func() time.Time { return now }
+	r.Save("a")
+	r.Save("b")
+	now = now.Add(time.Minute)
+	r.Save("c")
+	r.Close()
+	if files := r.Files(); len(files) != 2 {
+		t.Fatalf("got %v", files)
+	}
+	data, _ = ioutil.ReadFile(r.Files()[0])
+	if !strings.HasPrefix(string(data), "\"a\"\n\"b\"\n") {
+		t.Errorf("got %q", data)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "saver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := Create(filepath.Join(dir, "items.jsonl"), NewJSONLines)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Save(1)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadFile(filepath.Join(dir, "items.jsonl"))
+	if string(data) != "1\n" {
+		t.Errorf("got %q", data)
+	}
+}